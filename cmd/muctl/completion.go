@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"yuki.no/muon/protocol"
+)
+
+// Completion scripts are generated from the commands muon reports, so they
+// stay in step with the running version.
+
+// completions lists everything that can follow the command.
+func completions(info protocol.CommandInfo) []string {
+	words := append(append([]string{}, info.Flags...), info.Values...)
+	for _, sub := range info.Subcommands {
+		words = append(append(words, sub.Name), completions(sub)...)
+	}
+
+	return words
+}
+
+// flagsWithValue are the flags of muctl itself that take an argument.
+const flagsWithValue = "-network|-address|-timeout|-source|-format|-completion"
+
+func queryCommands(conn net.Conn) ([]protocol.CommandInfo, error) {
+	request := map[string]interface{}{
+		"version": protocolVersion,
+		"command": "query",
+		"args":    []string{"commands"},
+	}
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+
+	var res Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	if res.Status != "ok" {
+		return nil, fmt.Errorf("%s", res.Error)
+	}
+
+	var commands []protocol.CommandInfo
+	return commands, json.Unmarshal(res.Data, &commands)
+}
+
+func writeCompletion(w io.Writer, shell string, commands []protocol.CommandInfo) error {
+	switch shell {
+	case "bash":
+		writeBash(w, commands)
+	case "zsh":
+		writeZsh(w, commands)
+	case "fish":
+		writeFish(w, commands)
+	default:
+		return fmt.Errorf("unknown shell: %s", shell)
+	}
+
+	return nil
+}
+
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func writeBash(w io.Writer, commands []protocol.CommandInfo) {
+	var names []string
+	for _, info := range commands {
+		names = append(names, info.Name)
+	}
+
+	fmt.Fprint(w, `_muctl() {
+	local cur=${COMP_WORDS[COMP_CWORD]} command i
+
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		`+flagsWithValue+`) ((i++)) ;;
+		-*) ;;
+		*) command=${COMP_WORDS[i]}; break ;;
+		esac
+	done
+
+	case $command in
+`)
+	fmt.Fprintf(w, "\t\"\") COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", quote(strings.Join(names, " ")))
+
+	for _, info := range commands {
+		if words := completions(info); len(words) != 0 {
+			fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", info.Name, quote(strings.Join(words, " ")))
+		}
+	}
+
+	fmt.Fprint(w, `	esac
+}
+
+complete -F _muctl muctl
+`)
+}
+
+func writeZsh(w io.Writer, commands []protocol.CommandInfo) {
+	fmt.Fprint(w, `#compdef muctl
+
+_muctl() {
+	local -a commands
+	local command i
+
+	commands=(
+`)
+
+	for _, info := range commands {
+		fmt.Fprintf(w, "\t\t%s\n", quote(info.Name+":"+strings.Replace(info.Summary, ":", `\:`, -1)))
+	}
+
+	fmt.Fprint(w, `	)
+
+	for ((i = 2; i < CURRENT; i++)); do
+		case ${words[i]} in
+		`+flagsWithValue+`) ((i++)) ;;
+		-*) ;;
+		*) command=${words[i]}; break ;;
+		esac
+	done
+
+	if [[ -z $command ]]; then
+		_describe command commands
+		return
+	fi
+
+	case $command in
+`)
+
+	for _, info := range commands {
+		if words := completions(info); len(words) != 0 {
+			fmt.Fprintf(w, "\t%s) compadd -- %s ;;\n", info.Name, strings.Join(words, " "))
+		}
+	}
+
+	fmt.Fprint(w, `	esac
+}
+
+_muctl "$@"
+`)
+}
+
+func writeFish(w io.Writer, commands []protocol.CommandInfo) {
+	fmt.Fprintln(w, "complete -c muctl -f")
+
+	for _, info := range commands {
+		fmt.Fprintf(w, "complete -c muctl -n __fish_use_subcommand -a %s -d %s\n", info.Name, quote(info.Summary))
+	}
+
+	for _, info := range commands {
+		if words := completions(info); len(words) != 0 {
+			fmt.Fprintf(w, "complete -c muctl -n %s -a %s\n",
+				quote("__fish_seen_subcommand_from "+info.Name), quote(strings.Join(words, " ")),
+			)
+		}
+	}
+}
@@ -2,39 +2,301 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"strings"
+	"text/template"
 	"time"
 )
 
 var (
-	network = flag.String("network ", "unix", "")
-	address = flag.String("address ", "/tmp/muon", "")
-	timeout = flag.Duration("timeout", time.Second*5, "")
-	source  = flag.String("source", "", "")
+	network    = flag.String("network", "unix", "")
+	address    = flag.String("address", "/tmp/muon", "")
+	timeout    = flag.Duration("timeout", time.Second*5, "")
+	source     = flag.String("source", "", "")
+	format     = flag.String("format", "", "")
+	plain      = flag.Bool("plain", false, "")
+	completion = flag.String("completion", "", "")
 )
 
+// muctl exits with 1 when the command fails, and with 2 when muon can't be
+// reached or the reply can't be read.
+const (
+	exitFailure = 1
+	exitError   = 2
+)
+
+const protocolVersion = 1
+
+type Command struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+type Response struct {
+	Version int             `json:"version"`
+	Status  string          `json:"status"`
+	Error   string          `json:"error"`
+	Output  []string        `json:"output"`
+	Data    json.RawMessage `json:"data"`
+	Results []Response      `json:"results"`
+}
+
+type Event struct {
+	Topic string   `json:"topic"`
+	Args  []string `json:"args"`
+}
+
+func fatal(code int, err interface{}) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(code)
+}
+
 func main() {
 	flag.Parse()
 
+	if flag.NArg() == 0 && *source == "" && *completion == "" {
+		fatal(exitError, "usage: muctl [flags] command [args...]\n       muctl [flags] -source file|-\n       muctl [flags] -completion bash|zsh|fish")
+	}
+
+	if *plain && *source != "" {
+		fatal(exitError, "-source can't be combined with -plain")
+	}
+
+	var tmpl *template.Template
+	if *format != "" {
+		var err error
+		if tmpl, err = template.New("format").Parse(*format); err != nil {
+			fatal(exitError, err)
+		}
+	}
+
 	conn, err := net.DialTimeout(*network, *address, *timeout)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fatal(exitError, err)
 	}
 
-	conn.SetDeadline(time.Now().Add(*timeout))
 	defer conn.Close()
 
-	args := strings.Join(os.Args[1:], " ")
-	fmt.Fprintln(conn, args)
+	// Subscriptions stream events until interrupted, so only the request
+	// itself is subject to the timeout.
+	subscribe := flag.Arg(0) == "subscribe"
+	if subscribe {
+		conn.SetWriteDeadline(time.Now().Add(*timeout))
+	} else {
+		conn.SetDeadline(time.Now().Add(*timeout))
+	}
+
+	if *completion != "" {
+		commands, err := queryCommands(conn)
+		if err != nil {
+			fatal(exitError, err)
+		}
+
+		if err := writeCompletion(os.Stdout, *completion, commands); err != nil {
+			fatal(exitError, err)
+		}
+
+		return
+	}
+
+	if *plain {
+		runPlain(conn, tmpl)
+		return
+	}
+
+	if *source != "" {
+		runSource(conn, tmpl)
+		return
+	}
+
+	request := map[string]interface{}{
+		"version": protocolVersion,
+		"command": flag.Arg(0),
+		"args":    flag.Args()[1:],
+	}
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		fatal(exitError, err)
+	}
+
+	decoder := json.NewDecoder(conn)
+
+	var res Response
+	if err := decoder.Decode(&res); err != nil {
+		fatal(exitError, err)
+	}
+
+	for _, line := range res.Output {
+		fmt.Println(line)
+	}
+
+	if res.Status != "ok" {
+		fatal(exitFailure, res.Error)
+	}
 
-	reader := bufio.NewReader(conn)
-	response, _ := reader.ReadString('\n')
-	if actual := strings.TrimSpace(response); actual != "" {
-		fmt.Println(actual)
+	if len(res.Data) != 0 {
+		if err := render(tmpl, res.Data); err != nil {
+			fatal(exitError, err)
+		}
 	}
+
+	for subscribe {
+		var event json.RawMessage
+		if err := decoder.Decode(&event); err == io.EOF {
+			return
+		} else if err != nil {
+			fatal(exitError, err)
+		}
+
+		if err := renderEvent(tmpl, event); err != nil {
+			fatal(exitError, err)
+		}
+	}
+}
+
+// runSource sends a script with one command per line as a single batch, so
+// that muon applies it without handling any events in between.
+func runSource(conn net.Conn, tmpl *template.Template) {
+	var (
+		batch   = []Command{}
+		numbers []int
+	)
+
+	var input io.Reader = os.Stdin
+	if *source != "-" {
+		file, err := os.Open(*source)
+		if err != nil {
+			fatal(exitError, err)
+		}
+
+		defer file.Close()
+		input = file
+	}
+
+	scanner := bufio.NewScanner(input)
+	for number := 1; scanner.Scan(); number++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		batch = append(batch, Command{Command: fields[0], Args: fields[1:]})
+		numbers = append(numbers, number)
+	}
+
+	if err := scanner.Err(); err != nil {
+		fatal(exitError, err)
+	}
+
+	request := map[string]interface{}{
+		"version": protocolVersion,
+		"batch":   batch,
+	}
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		fatal(exitError, err)
+	}
+
+	var res Response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		fatal(exitError, err)
+	}
+
+	for i, result := range res.Results {
+		for _, line := range result.Output {
+			fmt.Println(line)
+		}
+
+		if len(result.Data) != 0 {
+			if err := render(tmpl, result.Data); err != nil {
+				fatal(exitError, err)
+			}
+		}
+
+		if result.Status != "ok" && i < len(numbers) {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", *source, numbers[i], result.Error)
+		}
+	}
+
+	if res.Status != "ok" {
+		if len(res.Results) == 0 {
+			fmt.Fprintln(os.Stderr, res.Error)
+		}
+
+		os.Exit(exitFailure)
+	}
+}
+
+// runPlain uses the plain text protocol, where failures can't be told apart
+// from output.
+func runPlain(conn net.Conn, tmpl *template.Template) {
+	// Muon closes the connection once it has answered the request.
+	fmt.Fprintln(conn, strings.Join(flag.Args(), " "))
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 1<<24)
+
+	for scanner.Scan() {
+		actual := strings.TrimSpace(scanner.Text())
+		if actual == "" {
+			continue
+		}
+
+		if tmpl == nil || !json.Valid([]byte(actual)) {
+			fmt.Println(actual)
+			continue
+		}
+
+		if err := render(tmpl, []byte(actual)); err != nil {
+			fatal(exitError, err)
+		}
+	}
+}
+
+// render prints a JSON payload through the template, once for every element
+// of an array, or as it is without one.
+func render(tmpl *template.Template, data []byte) error {
+	if tmpl == nil {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	for _, value := range values {
+		if err := tmpl.Execute(os.Stdout, value); err != nil {
+			return err
+		}
+
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func renderEvent(tmpl *template.Template, data []byte) error {
+	if tmpl != nil {
+		return render(tmpl, data)
+	}
+
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+
+	fmt.Println(strings.Join(append([]string{event.Topic}, event.Args...), " "))
+	return nil
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yuki.no/muon/muon"
+)
+
+// Commands can be chained like in a shell. Steps separated by ; always run,
+// a step after && only runs if the previous one succeeded, and a step after ||
+// only if it failed. Operators stand on their own or end a word, so that
+// arguments like -title a;b are left alone. Every step sees the state left by
+// the previous one, and acts on the monitor the chain started on unless a step
+// moves focus to another monitor.
+//
+//	focus-monitor +1 && focus-window urgent || focus-workspace 1
+//
+// Aliases expand to a chain, and take the rest of the line as their
+// definition:
+//
+//	alias zoom = root-window -focus; fullscreen false
+
+const maxAliasDepth = 16
+
+var aliases = make(map[string][]string)
+
+type step struct {
+	Operator string
+	Fields   []string
+}
+
+var operators = []string{";", "&&", "||"}
+
+// splitOperator splits the operator off the end of a field.
+func splitOperator(field string) (string, string) {
+	for _, operator := range operators {
+		if strings.HasSuffix(field, operator) {
+			return strings.TrimSuffix(field, operator), operator
+		}
+	}
+
+	return field, ""
+}
+
+// parseChain splits fields into steps at operators that stand on their own or
+// end a field, so that arguments containing them are left alone.
+func parseChain(fields []string) []step {
+	var (
+		steps   []step
+		current = step{Operator: ";"}
+	)
+
+	for i, field := range fields {
+		if len(current.Fields) != 0 && current.Fields[0] == "alias" {
+			current.Fields = append(current.Fields, aliasArgs(fields[i:])...)
+			break
+		}
+
+		field, operator := splitOperator(field)
+		if field != "" {
+			current.Fields = append(current.Fields, field)
+		}
+
+		if operator == "" {
+			continue
+		}
+
+		if len(current.Fields) != 0 {
+			steps = append(steps, current)
+		}
+
+		current = step{Operator: operator}
+	}
+
+	if len(current.Fields) != 0 {
+		steps = append(steps, current)
+	}
+
+	return steps
+}
+
+// aliasArgs keeps the definition of an alias from being parsed as flags.
+func aliasArgs(args []string) []string {
+	for i, arg := range args {
+		if arg == "=" {
+			return append(append(args[:i:i], "--"), args[i:]...)
+		}
+	}
+
+	return args
+}
+
+func executeChain(manager *muon.Manager, req Request, focusedMonitor *muon.Monitor, focusedWindow *muon.Window) error {
+	return runChain(manager, req, append([]string{req.Command}, req.Args...), 0, focusedMonitor, focusedWindow)
+}
+
+func runChain(manager *muon.Manager, req Request, fields []string, depth int, focusedMonitor *muon.Monitor, focusedWindow *muon.Window) error {
+	var (
+		err     error
+		focused = manager.Focused()
+		steps   = parseChain(fields)
+		last    = req.last
+	)
+
+	for i, step := range steps {
+		if (step.Operator == "&&" && err != nil) || (step.Operator == "||" && err == nil) {
+			continue
+		}
+
+		if i > 0 {
+			if mon := manager.Focused(); mon != focused {
+				focusedMonitor, focused = mon, mon
+			}
+
+			focusedWindow = nil
+			if focusedMonitor != nil {
+				focusedWindow = focusedMonitor.Focused()
+			}
+		}
+
+		req.last = last && i == len(steps)-1
+
+		if definition, ok := aliases[step.Fields[0]]; ok {
+			if depth >= maxAliasDepth {
+				return fmt.Errorf("alias nested too deep: %s", step.Fields[0])
+			}
+
+			expanded := append(append([]string{}, definition...), step.Fields[1:]...)
+			err = runChain(manager, req, expanded, depth+1, focusedMonitor, focusedWindow)
+			continue
+		}
+
+		req.Command, req.Args = step.Fields[0], step.Fields[1:]
+		err = execute(manager, req, focusedMonitor, focusedWindow)
+	}
+
+	return err
+}
+
+// alias [-remove] [name [= command...]]
+
+type AliasCmd struct {
+	Remove     bool
+	Name       string   `arg:"positional"`
+	Definition []string `arg:"positional"`
+}
+
+func (cmd AliasCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	switch {
+	case cmd.Name == "":
+		var names []string
+		for name := range aliases {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(req, name, "=", strings.Join(aliases[name], " "))
+		}
+
+	case cmd.Remove:
+		if _, ok := aliases[cmd.Name]; !ok {
+			return selectedWindow, fmt.Errorf("alias not found: %s", cmd.Name)
+		}
+
+		delete(aliases, cmd.Name)
+
+	case len(cmd.Definition) == 0:
+		definition, ok := aliases[cmd.Name]
+		if !ok {
+			return selectedWindow, fmt.Errorf("alias not found: %s", cmd.Name)
+		}
+
+		fmt.Fprintln(req, strings.Join(definition, " "))
+
+	case cmd.Definition[0] != "=" || len(cmd.Definition) == 1:
+		return selectedWindow, fmt.Errorf("usage: alias name = command...")
+
+	case newCommand(cmd.Name) != nil:
+		return selectedWindow, fmt.Errorf("alias shadows a command: %s", cmd.Name)
+
+	default:
+		aliases[cmd.Name] = cmd.Definition[1:]
+	}
+
+	return selectedWindow, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseChain(t *testing.T) {
+	tests := []struct {
+		line  string
+		steps []step
+	}{
+		{"", nil},
+		{"ratio 0.6", []step{
+			{";", []string{"ratio", "0.6"}},
+		}},
+		{"ratio 0.6 ; mirror-layout true", []step{
+			{";", []string{"ratio", "0.6"}},
+			{";", []string{"mirror-layout", "true"}},
+		}},
+		{"focus-monitor +1 && focus-window urgent || focus-workspace 1", []step{
+			{";", []string{"focus-monitor", "+1"}},
+			{"&&", []string{"focus-window", "urgent"}},
+			{"||", []string{"focus-workspace", "1"}},
+		}},
+		{"; ratio 0.6 ; ; mirror-layout true ;", []step{
+			{";", []string{"ratio", "0.6"}},
+			{";", []string{"mirror-layout", "true"}},
+		}},
+		{"rule add -title a;b", []step{
+			{";", []string{"rule", "add", "-title", "a;b"}},
+		}},
+		{"rule add -title a&&b||c", []step{
+			{";", []string{"rule", "add", "-title", "a&&b||c"}},
+		}},
+		{"ratio 0.6; mirror-layout true", []step{
+			{";", []string{"ratio", "0.6"}},
+			{";", []string{"mirror-layout", "true"}},
+		}},
+		{"focus-monitor +1&& focus-window urgent|| focus-workspace 1;", []step{
+			{";", []string{"focus-monitor", "+1"}},
+			{"&&", []string{"focus-window", "urgent"}},
+			{"||", []string{"focus-workspace", "1"}},
+		}},
+		{"root-window -focus; fullscreen false", []step{
+			{";", []string{"root-window", "-focus"}},
+			{";", []string{"fullscreen", "false"}},
+		}},
+		{"alias zoom = root-window -focus; fullscreen false", []step{
+			{";", []string{"alias", "zoom", "--", "=", "root-window", "-focus;", "fullscreen", "false"}},
+		}},
+		{"focus-window last && alias zoom = fullscreen true", []step{
+			{";", []string{"focus-window", "last"}},
+			{"&&", []string{"alias", "zoom", "--", "=", "fullscreen", "true"}},
+		}},
+	}
+
+	for _, test := range tests {
+		if steps := parseChain(strings.Fields(test.line)); !reflect.DeepEqual(steps, test.steps) {
+			t.Errorf("parseChain(%q) = %v, want %v", test.line, steps, test.steps)
+		}
+	}
+
+	fields := []string{"rule", "add", "-title", "a ; b"}
+	if steps := parseChain(fields); len(steps) != 1 || !reflect.DeepEqual(steps[0].Fields, fields) {
+		t.Errorf("parseChain(%q) = %v, want one step", fields, steps)
+	}
+}
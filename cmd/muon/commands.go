@@ -1,8 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"net"
+	"io"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -20,584 +23,817 @@ func init() {
 }
 
 type Request struct {
-	net.Conn
+	io.Writer
+	Conn    io.WriteCloser
 	Command string
 	Args    []string
+	Batch   []Request
+	Version int
+	done    chan bool
+	last    bool
 }
 
-type Command interface {
-	Run(Request, *muon.Manager, *muon.Monitor, *muon.Window, *muon.Window) *muon.Window
+// Reply sends a structured payload, which becomes the data of JSON replies.
+// Plain text requests get it encoded as JSON on one line.
+func (req Request) Reply(value interface{}) error {
+	if res, ok := req.Writer.(*Response); ok && res.Version != 0 {
+		res.Data = value
+		return nil
+	}
+
+	return json.NewEncoder(req).Encode(value)
 }
 
-func isCount(arg string) bool {
-	return arg != "" && strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+")
+type Command interface {
+	Run(Request, *muon.Manager, *muon.Monitor, *muon.Window, *muon.Window) (*muon.Window, error)
 }
 
-func isHex(arg string) bool {
-	return arg != "" && strings.HasPrefix(arg, "0x")
+type MonitorTarget struct {
+	Monitor string
 }
 
-func prepareCommand(req Request) (Command, error) {
-	var cmd Command
-
-	switch req.Command {
-	case "selected-border":
-		cmd = &SelectedBorderCmd{}
-	case "normal-border":
-		cmd = &NormalBorderCmd{}
-	case "focused-border":
-		cmd = &FocusedBorderCmd{}
-	case "border-width":
-		cmd = &BorderWidthCmd{}
-	case "window-gap":
-		cmd = &WindowGapCmd{}
-	case "root-count":
-		cmd = &RootCountCmd{}
-	case "ratio":
-		cmd = &RatioCmd{}
-	case "padding":
-		cmd = &PaddingCmd{}
-	case "fullscreen":
-		cmd = &FullscreenCmd{}
-	case "select-layout":
-		cmd = &SelectLayoutCmd{}
-	case "reset-layout":
-		cmd = &ResetLayoutCmd{}
-	case "mirror-layout":
-		cmd = &MirrorLayoutCmd{}
-	case "focus-monitor":
-		cmd = &FocusMonitorCmd{}
-	case "focus-window":
-		cmd = &FocusWindowCmd{}
-	case "select-window":
-		cmd = &SelectWindowCmd{}
-	case "root-window":
-		cmd = &RootWindowCmd{}
-	case "move-window":
-		cmd = &MoveWindowCmd{}
-	case "close-window":
-		cmd = &CloseWindowCmd{}
-	default:
-		return nil, fmt.Errorf("command not found: %s", req.Command)
+func (target MonitorTarget) TargetMonitor(manager *muon.Manager, focusedMonitor *muon.Monitor) (*muon.Monitor, error) {
+	if target.Monitor == "" {
+		return focusedMonitor, nil
 	}
 
-	parser, err := arg.NewParser(arg.Config{Program: req.Command}, cmd)
-	if err != nil {
-		return nil, err
+	if mon := manager.FindMonitorString(target.Monitor); mon != nil {
+		return mon, nil
 	}
 
-	return cmd, parser.Parse(req.Args)
+	return nil, fmt.Errorf("monitor not found: %s", target.Monitor)
+}
+
+type MonitorTargeted interface {
+	TargetMonitor(*muon.Manager, *muon.Monitor) (*muon.Monitor, error)
 }
 
-func runCommand(manager *muon.Manager, req Request, focusedMonitor *muon.Monitor, focusedWindow, selectedWindow *muon.Window) *muon.Window {
-	defer req.Close()
+// WorkspaceScoped commands change settings of the focused workspace, and run
+// for every workspace in monitor sections of the config file.
+type WorkspaceScoped interface {
+	WorkspaceScoped() bool
+}
 
-	if focusedMonitor == nil {
-		return selectedWindow
-	}
+func workspaceScoped(fields []string) bool {
+	cmd, err := prepareCommand(Request{Command: fields[0], Args: fields[1:]})
+	scoped, ok := cmd.(WorkspaceScoped)
 
-	zap.S().Infow("request", "command", req.Command, "args", req.Args)
+	return err == nil && ok && scoped.WorkspaceScoped()
+}
 
-	cmd, err := prepareCommand(req)
-	if err != nil {
-		fmt.Fprintln(req, err.Error())
-		return selectedWindow
+func retarget(manager *muon.Manager, cmd Command, focusedMonitor *muon.Monitor, focusedWindow *muon.Window) (*muon.Monitor, *muon.Window, error) {
+	targeted, ok := cmd.(MonitorTargeted)
+	if !ok {
+		return focusedMonitor, focusedWindow, nil
+	}
+
+	mon, err := targeted.TargetMonitor(manager, focusedMonitor)
+	if err != nil || mon == focusedMonitor {
+		return focusedMonitor, focusedWindow, err
 	}
 
-	return cmd.Run(req, manager, focusedMonitor, focusedWindow, selectedWindow)
+	return mon, mon.Focused(), nil
 }
 
-// selected-border [color]
+func isCount(arg string) bool {
+	return arg != "" && strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+")
+}
 
-type SelectedBorderCmd struct {
-	Color string `arg:"positional"`
+func isWindowId(arg string) bool {
+	return strings.HasPrefix(arg, "0x") || strings.HasPrefix(arg, muon.MarkPrefix)
 }
 
-func (cmd SelectedBorderCmd) Run(req Request,
-	manager *muon.Manager, focusedMonitor *muon.Monitor,
-	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Color == "":
-		fmt.Fprintln(req, manager.SelectedBorder.String)
+func isDirection(arg string) bool {
+	_, ok := muon.ParseDirection(arg)
+	return ok
+}
 
-	case cmd.Color != "" && selectedWindow != nil:
-		manager.SelectedBorder = muon.NewColor(cmd.Color)
-		xlib.SetBorderColor(selectedWindow.Id, manager.SelectedBorder.Value)
+// countMark is put in front of negative counts like -1, which would be
+// parsed as flags otherwise.
+const countMark = "\x00"
+
+// escapeCounts marks negative counts, so that they are parsed as positionals
+// while the flags around them still parse.
+func escapeCounts(args []string) []string {
+	escaped := make([]string, len(args))
+	for i, arg := range args {
+		if _, err := strconv.ParseFloat(arg, 64); err == nil && strings.HasPrefix(arg, "-") {
+			arg = countMark + arg
+		}
 
-	case cmd.Color != "":
-		manager.SelectedBorder = muon.NewColor(cmd.Color)
+		escaped[i] = arg
 	}
 
-	return selectedWindow
+	return escaped
 }
 
-// normal-border [color]
+// unescapeCounts removes the marks of escapeCounts from the parsed arguments.
+func unescapeCounts(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			unescapeCounts(v.Elem())
+		}
 
-type NormalBorderCmd struct {
-	Color string `arg:"positional"`
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				unescapeCounts(field)
+			}
+		}
+
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			unescapeCounts(v.Index(i))
+		}
+
+	case reflect.String:
+		v.SetString(strings.TrimPrefix(v.String(), countMark))
+	}
 }
 
-func (cmd NormalBorderCmd) Run(req Request,
-	manager *muon.Manager, focusedMonitor *muon.Monitor,
-	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+// targetWindow finds the window a -window flag refers to, which defaults to
+// the selected or focused window.
+func targetWindow(manager *muon.Manager, selector string, focusedWindow, selectedWindow *muon.Window) (*muon.Window, error) {
+	var win *muon.Window
+
 	switch {
-	case cmd.Color == "":
-		fmt.Fprintln(req, manager.NormalBorder.String)
+	case selector == "" && selectedWindow != nil:
+		return selectedWindow, nil
+	case selector == "":
+		return focusedWindow, nil
+	case selector == "pointer":
+		win, _, _ = manager.FindWindowPointer()
+	case isWindowId(selector):
+		win, _, _ = manager.FindWindowString(selector)
+	default:
+		return nil, fmt.Errorf("invalid window: %s", selector)
+	}
 
-	case cmd.Color != "":
-		manager.NormalBorder = muon.NewColor(cmd.Color)
+	if win == nil {
+		return nil, fmt.Errorf("window not found: %s", selector)
+	}
 
-		for _, mon := range manager.Monitors.All() {
-			for _, win := range mon.Windows.All() {
-				if win != mon.Focused() && win != selectedWindow {
-					xlib.SetBorderColor(win.Id, manager.NormalBorder.Value)
-				}
-			}
+	return win, nil
+}
+
+func findWorkspace(mon *muon.Monitor, selector string) *muon.Workspace {
+	if isCount(selector) {
+		if count, err := strconv.Atoi(selector); err == nil {
+			return mon.Workspaces.Select(count)
 		}
+
+		return nil
 	}
 
-	return selectedWindow
-}
+	for _, ws := range mon.Workspaces.All() {
+		if ws.Name == selector {
+			return ws
+		}
+	}
 
-// focused-border [color]
+	if index, err := strconv.Atoi(selector); err == nil && index >= 1 && index <= mon.Workspaces.Len() {
+		return mon.Workspaces.All()[index-1]
+	}
 
-type FocusedBorderCmd struct {
-	Color string `arg:"positional"`
+	return nil
 }
 
-func (cmd FocusedBorderCmd) Run(req Request,
-	manager *muon.Manager, focusedMonitor *muon.Monitor,
-	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Color == "":
-		fmt.Fprintln(req, manager.FocusedBorder.String)
+func prepareCommand(req Request) (Command, error) {
+	cmd := newCommand(req.Command)
+	if cmd == nil {
+		return nil, fmt.Errorf("command not found: %s", req.Command)
+	}
 
-	case cmd.Color != "":
-		manager.FocusedBorder = muon.NewColor(cmd.Color)
+	parser, err := arg.NewParser(arg.Config{Program: req.Command}, cmd)
+	if err != nil {
+		return nil, err
+	}
 
-		if mon := manager.Focused(); mon != nil {
-			if win := mon.Focused(); win != nil {
-				xlib.SetBorderColor(win.Id, manager.FocusedBorder.Value)
-			}
-		}
+	err = parser.Parse(escapeCounts(req.Args))
+	unescapeCounts(reflect.ValueOf(cmd))
+
+	if err != nil && strings.Contains(err.Error(), countMark) {
+		err = fmt.Errorf("%s", strings.Replace(err.Error(), countMark, "", -1))
 	}
 
-	return selectedWindow
+	return cmd, err
 }
 
-// border-width [-default] [size]
+func runCommand(manager *muon.Manager, req Request, focusedMonitor *muon.Monitor, focusedWindow *muon.Window) {
+	res := &Response{Version: req.Version}
+	req.Writer = res
 
-type BorderWidthCmd struct {
-	Default bool
-	Size    string `arg:"positional"`
+	defer func() {
+		res.Send(req.Conn)
+		req.done <- subscribed(req)
+	}()
+
+	if req.Batch != nil {
+		runBatch(manager, req, res)
+		return
+	}
+
+	req.last = true
+	res.Finish(executeChain(manager, req, focusedMonitor, focusedWindow))
 }
 
-func (cmd BorderWidthCmd) Run(req Request,
-	manager *muon.Manager, focusedMonitor *muon.Monitor,
-	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Size == "" && cmd.Default:
-		fmt.Fprintln(req, manager.BorderWidth)
+// runBatch runs every command in the batch, even after one of them has
+// failed, and arranges the monitors once at the end.
+func runBatch(manager *muon.Manager, req Request, res *Response) {
+	var failed error
+
+	manager.Hold()
+	defer manager.Release()
 
-	case cmd.Size == "":
-		fmt.Fprintln(req, focusedMonitor.BorderWidth)
+	for i, batched := range req.Batch {
+		result := &Response{Version: req.Version}
+		batched.Writer = result
+		batched.last = i == len(req.Batch)-1
 
-	case cmd.Size != "" && cmd.Default:
-		if value, err := strconv.Atoi(cmd.Size); err == nil {
-			manager.BorderWidth = value
+		var (
+			focusedMonitor = manager.Focused()
+			focusedWindow  *muon.Window
+			err            error
+		)
+
+		if focusedMonitor != nil {
+			focusedWindow = focusedMonitor.Focused()
 		}
 
-	case cmd.Size != "":
-		if value, err := strconv.Atoi(cmd.Size); err == nil {
-			focusedMonitor.BorderWidth = value
-			focusedMonitor.Arrange()
+		err = executeChain(manager, batched, focusedMonitor, focusedWindow)
+		if err != nil && failed == nil {
+			failed = fmt.Errorf("%d: %s", i+1, err)
 		}
+
+		result.Finish(err)
+		res.Results = append(res.Results, result)
 	}
 
-	return selectedWindow
+	res.Finish(failed)
 }
 
-// window-gap [-default] [size]
+// execute runs a command on the current selection, and applies the selection
+// it returns.
+func execute(manager *muon.Manager, req Request, focusedMonitor *muon.Monitor, focusedWindow *muon.Window) error {
+	if focusedMonitor == nil {
+		return fmt.Errorf("no monitor")
+	}
 
-type WindowGapCmd struct {
-	Default bool
-	Size    string `arg:"positional"`
+	zap.S().Infow("request", "command", req.Command, "args", req.Args)
+
+	cmd, err := prepareCommand(req)
+	if err == arg.ErrHelp {
+		return writeHelp(req, findCommand(req.Command))
+	} else if err != nil {
+		return err
+	}
+
+	focusedMonitor, focusedWindow, err = retarget(manager, cmd, focusedMonitor, focusedWindow)
+	if err != nil {
+		return err
+	}
+
+	win, err := cmd.Run(req, manager, focusedMonitor, focusedWindow, lastSelected())
+	updateSelection(win)
+
+	return err
+}
+
+// padding [-monitor name|index|pointer] [-default] left|right|top|bottom [-N|+N|size|default]
+
+type PaddingCmd struct {
+	MonitorTarget
+	Default   bool
+	Direction string `arg:"positional"`
+	Size      string `arg:"positional"`
 }
 
-func (cmd WindowGapCmd) Run(req Request,
+func (cmd PaddingCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Size == "" && cmd.Default:
-		fmt.Fprintln(req, manager.WindowGap)
+) (*muon.Window, error) {
+	prop := muon.FindProperty("padding-" + cmd.Direction)
+	if prop == nil || cmd.Direction == "" {
+		return selectedWindow, fmt.Errorf("invalid direction: %s", cmd.Direction)
+	}
+
+	t := muon.Target{Manager: manager, Monitor: focusedMonitor, Selected: selection, Default: cmd.Default}
+	return selectedWindow, changeProperty(req, prop, t, cmd.Size)
+}
 
-	case cmd.Size == "":
-		fmt.Fprintln(req, focusedMonitor.WindowGap)
+// select-layout [-monitor name|index|pointer] [-N|+N|name]
 
-	case cmd.Size != "" && cmd.Default:
-		if value, err := strconv.Atoi(cmd.Size); err == nil {
-			manager.WindowGap = value
+type SelectLayoutCmd struct {
+	MonitorTarget
+	Layout string `arg:"positional"`
+}
+
+func (cmd SelectLayoutCmd) WorkspaceScoped() bool {
+	return cmd.Layout != ""
+}
+
+func (cmd SelectLayoutCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
+
+	switch {
+	case cmd.Layout == "":
+		if layout := focusedWorkspace.Layouts.Focused(); layout != nil {
+			fmt.Fprintln(req, layout.String())
 		}
 
-	case cmd.Size != "":
-		if value, err := strconv.Atoi(cmd.Size); err == nil {
-			focusedMonitor.WindowGap = value
+	case isCount(cmd.Layout):
+		if count, err := strconv.Atoi(cmd.Layout); err == nil {
+			focusedWorkspace.Layouts.Focus(count)
 			focusedMonitor.Arrange()
 		}
 
+	case cmd.Layout != "":
+		previousLayout := focusedWorkspace.Layouts.Focused()
+		focusedWorkspace.Layouts.FocusFunc(func(layout muon.Layout) bool {
+			return layout.String() == cmd.Layout
+		})
+
+		if focusedWorkspace.Layouts.Focused() != previousLayout {
+			focusedMonitor.Arrange()
+		}
 	}
 
-	return selectedWindow
+	return selectedWindow, nil
 }
 
-// root-count [-N|+N|count]
+// reset-layout [-monitor name|index|pointer]
 
-type RootCountCmd struct {
-	Count string `arg:"positional"`
+type ResetLayoutCmd struct {
+	MonitorTarget
 }
 
-func (cmd RootCountCmd) Run(req Request,
+func (cmd ResetLayoutCmd) WorkspaceScoped() bool {
+	return true
+}
+
+func (cmd ResetLayoutCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Count == "":
-		fmt.Fprintln(req, focusedMonitor.RootCount)
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
 
-	case isCount(cmd.Count):
-		if count, err := strconv.Atoi(cmd.Count); err == nil {
-			if newCount := focusedMonitor.RootCount + count; newCount >= 1 && newCount <= focusedMonitor.Windows.Len() {
-				focusedMonitor.RootCount = newCount
-				focusedMonitor.Arrange()
-			}
-		}
+	focusedWorkspace.Reset(manager)
+	focusedMonitor.Arrange()
 
-	case cmd.Count != "":
-		if count, err := strconv.Atoi(cmd.Count); err == nil {
-			if count >= 1 && count <= focusedMonitor.Windows.Len() {
-				focusedMonitor.RootCount = count
-				focusedMonitor.Arrange()
-			}
-		}
+	return selectedWindow, nil
+}
 
-	}
+// layouts [-monitor name|index|pointer] [-default] [name...]
 
-	return selectedWindow
+type LayoutsCmd struct {
+	MonitorTarget
+	Default bool
+	Names   []string `arg:"positional"`
 }
 
-// ratio [size]
-
-type RatioCmd struct {
-	Ratio string `arg:"positional"`
+func (cmd LayoutsCmd) WorkspaceScoped() bool {
+	return len(cmd.Names) != 0 && !cmd.Default
 }
 
-func (cmd RatioCmd) Run(req Request,
+func (cmd LayoutsCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
+
 	switch {
-	case cmd.Ratio == "":
-		fmt.Fprintln(req, focusedMonitor.Ratio)
+	case len(cmd.Names) == 0 && cmd.Default:
+		fmt.Fprintln(req, strings.Join(manager.Layouts, " "))
 
-	case isCount(cmd.Ratio):
-		if size, err := strconv.ParseFloat(cmd.Ratio, 64); err == nil {
-			if newSize := focusedMonitor.Ratio + size; newSize >= 0.2 && newSize <= 0.8 {
-				focusedMonitor.Ratio = newSize
-				focusedMonitor.Arrange()
-			}
+	case len(cmd.Names) == 0:
+		var names []string
+		for _, layout := range focusedWorkspace.Layouts.All() {
+			names = append(names, layout.String())
 		}
 
-	case cmd.Ratio != "":
-		if size, err := strconv.ParseFloat(req.Args[0], 64); err == nil {
-			if size >= 0.2 && size <= 0.8 {
-				focusedMonitor.Ratio = size
-				focusedMonitor.Arrange()
+		fmt.Fprintln(req, strings.Join(names, " "))
+
+	default:
+		layouts := muon.NewLayoutList()
+		for _, name := range cmd.Names {
+			layout := muon.NewLayout(name)
+			if layout == nil {
+				return selectedWindow, fmt.Errorf("layout not found: %s", name)
 			}
+
+			layouts.Insert(layout)
 		}
 
+		if cmd.Default {
+			manager.Layouts = cmd.Names
+		} else {
+			focusedWorkspace.Layouts = layouts
+			focusedMonitor.Arrange()
+		}
 	}
 
-	return selectedWindow
+	return selectedWindow, nil
 }
 
-// padding left|right|top|bottom [size]
+// focus-monitor [-N|+N|name|index|pointer]
 
-type PaddingCmd struct {
-	Direction string `arg:"positional"`
-	Size      string `arg:"positional"`
+type FocusMonitorCmd struct {
+	Selector string `arg:"positional"`
 }
 
-func (cmd PaddingCmd) Run(req Request,
+func (cmd FocusMonitorCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
 	switch {
-	case cmd.Direction == "left" && cmd.Size == "":
-		fmt.Fprintln(req, focusedMonitor.Padding.L)
+	case cmd.Selector == "":
+		fmt.Fprintln(req, focusedMonitor)
 
-	case cmd.Direction == "left" && cmd.Size != "":
-		if padding, err := strconv.Atoi(cmd.Size); err == nil {
-			focusedMonitor.Padding.L = padding
-			focusedMonitor.Arrange()
+	case isCount(cmd.Selector):
+		if count, err := strconv.Atoi(cmd.Selector); err == nil {
+			manager.Monitors.Focus(count)
 		}
 
-	case cmd.Direction == "right" && cmd.Size == "":
-		fmt.Fprintln(req, focusedMonitor.Padding.R)
-
-	case cmd.Direction == "right" && cmd.Size != "":
-		if padding, err := strconv.Atoi(cmd.Size); err == nil {
-			focusedMonitor.Padding.R = padding
-			focusedMonitor.Arrange()
+	case cmd.Selector != "":
+		if mon := manager.FindMonitorString(cmd.Selector); mon != nil {
+			manager.Monitors.FocusMatch(mon)
 		}
+	}
 
-	case cmd.Direction == "top" && cmd.Size == "":
-		fmt.Fprintln(req, focusedMonitor.Padding.T)
+	return selectedWindow, nil
+}
 
-	case cmd.Direction == "top" && cmd.Size != "":
-		if padding, err := strconv.Atoi(cmd.Size); err == nil {
-			focusedMonitor.Padding.T = padding
-			focusedMonitor.Arrange()
-		}
+// focus-workspace [-monitor name|index|pointer] [-N|+N|index|name]
 
-	case cmd.Direction == "bottom" && cmd.Size == "":
-		fmt.Fprintln(req, focusedMonitor.Padding.B)
+type FocusWorkspaceCmd struct {
+	MonitorTarget
+	Workspace string `arg:"positional"`
+}
 
-	case cmd.Direction == "bottom" && cmd.Size != "":
-		if padding, err := strconv.Atoi(cmd.Size); err == nil {
-			focusedMonitor.Padding.B = padding
-			focusedMonitor.Arrange()
+func (cmd FocusWorkspaceCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	switch {
+	case cmd.Workspace == "":
+		fmt.Fprintln(req, focusedMonitor.Workspace().Name)
+
+	case cmd.Workspace != "":
+		if ws := findWorkspace(focusedMonitor, cmd.Workspace); ws != nil {
+			focusedMonitor.FocusWorkspace(ws)
 		}
 	}
 
-	return selectedWindow
+	return selectedWindow, nil
 }
 
-// fullscreen [ false|true|toggle ]
+// send-to-workspace [-follow] -N|+N|index|name
 
-type FullscreenCmd struct {
-	State string `arg:"positional"`
+type SendToWorkspaceCmd struct {
+	Follow    bool
+	Workspace string `arg:"positional"`
 }
 
-func (cmd FullscreenCmd) Run(req Request,
+func (cmd SendToWorkspaceCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.State == "" && focusedMonitor.Fullscreen:
-		fmt.Fprintln(req, "true")
+) (*muon.Window, error) {
+	windows := selection
+	if len(windows) == 0 && focusedWindow != nil {
+		windows = []*muon.Window{focusedWindow}
+	}
 
-	case cmd.State == "":
-		fmt.Fprintln(req, "false")
+	if len(windows) == 0 || cmd.Workspace == "" {
+		return selectedWindow, nil
+	}
 
-	case cmd.State == "false":
-		if focusedMonitor.Fullscreen {
-			focusedMonitor.Fullscreen = false
-			focusedMonitor.Arrange()
+	for _, win := range windows {
+		_, _, mon := manager.FindWindow(win.Id)
+		if mon == nil {
+			continue
 		}
 
-	case cmd.State == "true":
-		if !focusedMonitor.Fullscreen {
-			focusedMonitor.Fullscreen = true
-			focusedMonitor.Arrange()
+		ws := findWorkspace(mon, cmd.Workspace)
+		if ws == nil {
+			continue
 		}
 
-	case cmd.State == "toggle":
-		focusedMonitor.Fullscreen = !focusedMonitor.Fullscreen
-		focusedMonitor.Arrange()
+		manager.MoveWindow(win, ws, mon)
+
+		if cmd.Follow {
+			manager.Monitors.FocusMatch(mon)
+			mon.FocusWorkspace(ws)
+			ws.Windows.FocusMatch(win)
+
+			if ws.Fullscreen {
+				mon.Arrange()
+			}
+		}
 	}
 
-	return selectedWindow
+	return nil, nil
 }
 
-// select-layout [-N|+N|name]
+// send-window [-follow] [-window pointer|id|mark:NAME] -N|+N|name|index|pointer
 
-type SelectLayoutCmd struct {
-	Layout string `arg:"positional"`
+type SendWindowCmd struct {
+	Follow  bool
+	Window  string
+	Monitor string `arg:"positional"`
 }
 
-func (cmd SelectLayoutCmd) Run(req Request,
+func (cmd SendWindowCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Layout == "":
-		if layout := focusedMonitor.Layouts.Focused(); layout != nil {
-			fmt.Fprintln(req, layout.String())
+) (*muon.Window, error) {
+	windows := selection
+	if cmd.Window != "" || len(windows) == 0 {
+		win, err := targetWindow(manager, cmd.Window, focusedWindow, selectedWindow)
+		if err != nil {
+			return selectedWindow, err
 		}
 
-	case isCount(cmd.Layout):
-		if count, err := strconv.Atoi(cmd.Layout); err == nil {
-			focusedMonitor.Layouts.Focus(count)
-			focusedMonitor.Arrange()
+		windows = nil
+		if win != nil {
+			windows = []*muon.Window{win}
 		}
+	}
 
-	case cmd.Layout != "":
-		previousLayout := focusedMonitor.Layouts.Focused()
-		focusedMonitor.Layouts.FocusFunc(func(layout muon.Layout) bool {
-			return layout.String() == cmd.Layout
-		})
+	if len(windows) == 0 || cmd.Monitor == "" {
+		return selectedWindow, nil
+	}
 
-		if focusedMonitor.Layouts.Focused() != previousLayout {
-			focusedMonitor.Arrange()
+	var mon *muon.Monitor
+	if isCount(cmd.Monitor) {
+		if count, err := strconv.Atoi(cmd.Monitor); err == nil {
+			mon = manager.Monitors.Select(count)
 		}
+	} else {
+		mon = manager.FindMonitorString(cmd.Monitor)
 	}
 
-	return selectedWindow
-}
+	if mon == nil {
+		return selectedWindow, fmt.Errorf("monitor not found: %s", cmd.Monitor)
+	}
 
-// reset-layout
+	ws := mon.Workspace()
+	for _, win := range windows {
+		manager.MoveWindow(win, ws, mon)
+	}
 
-type ResetLayoutCmd struct {
-}
+	if cmd.Follow {
+		manager.Monitors.FocusMatch(mon)
+		ws.Windows.FocusMatch(windows[len(windows)-1])
 
-func (cmd ResetLayoutCmd) Run(req Request,
-	manager *muon.Manager, focusedMonitor *muon.Monitor,
-	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	focusedMonitor.Reset()
-	focusedMonitor.Arrange()
+		if ws.Fullscreen {
+			mon.Arrange()
+		}
+	}
 
-	return selectedWindow
+	return nil, nil
 }
 
-// mirror-layout [false|true|toggle]
+// swap-monitors [-layouts] [-N|+N|name|index|pointer]
 
-type MirrorLayoutCmd struct {
-	State string `arg:"positional"`
+type SwapMonitorsCmd struct {
+	Layouts bool
+	Monitor string `arg:"positional"`
 }
 
-func (cmd MirrorLayoutCmd) Run(req Request,
+func (cmd SwapMonitorsCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.State == "" && focusedMonitor.Mirrored:
-		fmt.Fprintln(req, "true")
-
-	case cmd.State == "":
-		fmt.Fprintln(req, "false")
+) (*muon.Window, error) {
+	var mon *muon.Monitor
 
-	case cmd.State == "false":
-		if focusedMonitor.Mirrored {
-			focusedMonitor.Mirrored = false
-			focusedMonitor.Arrange()
+	switch {
+	case cmd.Monitor == "":
+		mon = manager.Monitors.Select(1)
+	case isCount(cmd.Monitor):
+		if count, err := strconv.Atoi(cmd.Monitor); err == nil {
+			mon = manager.Monitors.Select(count)
 		}
+	default:
+		mon = manager.FindMonitorString(cmd.Monitor)
+	}
 
-	case cmd.State == "true":
-		if !focusedMonitor.Mirrored {
-			focusedMonitor.Mirrored = true
-			focusedMonitor.Arrange()
-		}
+	if mon == nil || mon == focusedMonitor {
+		return selectedWindow, nil
+	}
 
-	case cmd.State == "toggle":
-		focusedMonitor.Mirrored = !focusedMonitor.Mirrored
-		focusedMonitor.Arrange()
+	manager.SwapMonitors(focusedMonitor, mon, cmd.Layouts)
 
+	if focusedWindow != nil {
+		manager.Monitors.FocusMatch(mon)
 	}
 
-	return selectedWindow
+	return selectedWindow, nil
 }
 
-// focus-monitor [-N|+N]
+// rename-workspace [-monitor name|index|pointer] [name]
 
-type FocusMonitorCmd struct {
-	Selector string `arg:"positional"`
+type RenameWorkspaceCmd struct {
+	MonitorTarget
+	Name string `arg:"positional"`
 }
 
-func (cmd FocusMonitorCmd) Run(req Request,
+func (cmd RenameWorkspaceCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
 	switch {
-	case isCount(cmd.Selector):
-		if count, err := strconv.Atoi(cmd.Selector); err == nil {
-			manager.Monitors.Focus(count)
-		}
+	case cmd.Name == "":
+		fmt.Fprintln(req, focusedMonitor.Workspace().Name)
+
+	case cmd.Name != "":
+		focusedMonitor.Workspace().Name = cmd.Name
 	}
 
-	return selectedWindow
+	return selectedWindow, nil
 }
 
-// focus-window [pointer|id|-N+|+N]
+// focus-window [pointer|urgent|last|left|right|up|down|id|mark:NAME|-N+|+N]
+// focus-window history -N|+N
 
 type FocusWindowCmd struct {
 	Selector string `arg:"positional"`
+	Count    string `arg:"positional"`
 }
 
 func (cmd FocusWindowCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
+
 	switch {
 	case cmd.Selector == "pointer":
-		if win, mon := manager.FindWindowPointer(); mon != nil && win != nil {
+		if win, ws, mon := manager.FindWindowPointer(); mon != nil && win != nil {
+			manager.Monitors.FocusMatch(mon)
+			ws.Windows.FocusMatch(win)
+
+			if ws.Fullscreen {
+				mon.Arrange()
+			}
+		}
+
+	case isDirection(cmd.Selector):
+		dir, _ := muon.ParseDirection(cmd.Selector)
+		if win, mon := manager.FindNeighbour(focusedMonitor, focusedWindow, dir); mon != nil {
 			manager.Monitors.FocusMatch(mon)
-			mon.Windows.FocusMatch(win)
 
-			if mon.Fullscreen {
+			if ws := mon.Workspace(); win != nil {
+				ws.Windows.FocusMatch(win)
+
+				if ws.Fullscreen {
+					mon.Arrange()
+				}
+			}
+		}
+
+	case cmd.Selector == "urgent":
+		if win, ws, mon := manager.FindUrgent(); mon != nil && win != nil {
+			manager.Monitors.FocusMatch(mon)
+			mon.FocusWorkspace(ws)
+			ws.Windows.FocusMatch(win)
+			win.SetUrgent(false)
+
+			if ws.Fullscreen {
 				mon.Arrange()
 			}
 		}
 
+	case cmd.Selector == "last":
+		focusWindow(manager, manager.LastWindow())
+
+	case cmd.Selector == "history":
+		count, err := strconv.Atoi(cmd.Count)
+		if err != nil {
+			return selectedWindow, fmt.Errorf("invalid count: %s", cmd.Count)
+		}
+
+		focusWindow(manager, manager.CycleHistory(count))
+
 	case isCount(cmd.Selector) && selectedWindow != nil:
-		focusedMonitor.Windows.FocusMatch(selectedWindow)
+		focusedWorkspace.Windows.FocusMatch(selectedWindow)
 
-		if focusedMonitor.Fullscreen {
+		if focusedWorkspace.Fullscreen {
 			focusedMonitor.Arrange()
 		}
 
 	case isCount(cmd.Selector):
 		if count, err := strconv.Atoi(cmd.Selector); err == nil {
-			focusedMonitor.Windows.Focus(count)
+			focusedWorkspace.Windows.Focus(count)
 
-			if focusedMonitor.Fullscreen {
+			if focusedWorkspace.Fullscreen {
 				focusedMonitor.Arrange()
 			}
 		}
 
-	case isHex(cmd.Selector):
-		if win, mon := manager.FindWindowString(cmd.Selector); mon != nil && win != nil {
-			mon.Windows.FocusMatch(win)
-
-			if mon.Fullscreen {
-				mon.Arrange()
-			}
+	case isWindowId(cmd.Selector):
+		win, _, _ := manager.FindWindowString(cmd.Selector)
+		if win == nil {
+			return selectedWindow, fmt.Errorf("window not found: %s", cmd.Selector)
 		}
+
+		focusWindow(manager, win)
 	}
 
-	return nil
+	return nil, nil
+}
+
+// focusWindow focuses a window wherever it is, showing its workspace.
+func focusWindow(manager *muon.Manager, win *muon.Window) {
+	if win == nil {
+		return
+	}
+
+	if _, ws, mon := manager.FindWindow(win.Id); mon != nil {
+		manager.Monitors.FocusMatch(mon)
+		mon.FocusWorkspace(ws)
+		ws.Windows.FocusMatch(win)
+
+		if ws.Fullscreen {
+			mon.Arrange()
+		}
+	}
 }
 
-// select-window [id|-N+|+N]
+// select-window [-add|-remove] [id|mark:NAME|-N+|+N|(focused)]
+// select-window -clear
 
 type SelectWindowCmd struct {
+	Add      bool
+	Remove   bool
+	Clear    bool
 	Selector string `arg:"positional"`
 }
 
 func (cmd SelectWindowCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
+
+	if cmd.Clear {
+		return nil, nil
+	}
+
+	var win *muon.Window
+
 	switch {
 	case isCount(cmd.Selector):
 		if count, err := strconv.Atoi(cmd.Selector); err == nil {
-			return focusedMonitor.Windows.Select(count)
+			win = focusedWorkspace.Windows.Select(count)
 		}
 
-	case isHex(cmd.Selector):
-		selectedWindow, _ = manager.FindWindowString(cmd.Selector)
-		return selectedWindow
+	case isWindowId(cmd.Selector):
+		if win, _, _ = manager.FindWindowString(cmd.Selector); win == nil {
+			return selectedWindow, fmt.Errorf("window not found: %s", cmd.Selector)
+		}
+
+	case cmd.Selector == "" && (cmd.Add || cmd.Remove):
+		win = focusedWindow
+
+	case cmd.Selector == "":
+		for _, win := range selection {
+			fmt.Fprintln(req, windowId(win.Id))
+		}
+
+		return selectedWindow, nil
+
+	default:
+		return selectedWindow, fmt.Errorf("invalid selector: %s", cmd.Selector)
 	}
 
-	return selectedWindow
+	switch {
+	case win == nil:
+		return selectedWindow, nil
+
+	case cmd.Add:
+		addSelection(win)
+		return win, nil
+
+	case cmd.Remove:
+		removeSelection(win)
+		return lastSelected(), nil
+	}
+
+	// Selecting without -add replaces the selection, even with a window that
+	// was already part of it.
+	selection = nil
+	return win, nil
 }
 
-// move-window [pointer|id|-N|+N|(selected)]
+// move-window [pointer|left|right|up|down|id|mark:NAME|-N|+N|(selected)]
+//
+// Directions and counts move every selected window, or the focused one. With
+// no selector, the focused window swaps places with each selected window in
+// turn.
 
 type MoveWindowCmd struct {
 	Selector string `arg:"positional"`
@@ -606,35 +842,104 @@ type MoveWindowCmd struct {
 func (cmd MoveWindowCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
+
 	switch {
 	case cmd.Selector == "pointer":
-		if win, mon := manager.FindWindowPointer(); mon != nil && win != nil {
-			mon.Windows.MoveFocusMatch(win)
+		if win, ws, mon := manager.FindWindowPointer(); mon != nil && win != nil {
+			ws.Windows.MoveFocusMatch(win)
+			mon.Arrange()
+		}
+
+	case isDirection(cmd.Selector) && selectedWindow != nil:
+		dir, _ := muon.ParseDirection(cmd.Selector)
+		for _, win := range selection {
+			moveInDirection(manager, win, dir)
+		}
+
+	case isDirection(cmd.Selector) && focusedWindow != nil:
+		dir, _ := muon.ParseDirection(cmd.Selector)
+		if mon := moveInDirection(manager, focusedWindow, dir); mon != nil && mon != focusedMonitor {
+			manager.Monitors.FocusMatch(mon)
+			mon.Workspace().Windows.FocusMatch(focusedWindow)
 			mon.Arrange()
 		}
 
+	case isCount(cmd.Selector) && selectedWindow != nil:
+		if count, err := strconv.Atoi(cmd.Selector); err == nil {
+			for _, win := range selection {
+				if _, ws, mon := manager.FindWindow(win.Id); mon != nil {
+					moveWindow(ws, win, func() { ws.Windows.MoveFocus(count) })
+					mon.Arrange()
+				}
+			}
+		}
+
 	case isCount(cmd.Selector):
 		if count, err := strconv.Atoi(cmd.Selector); err == nil {
-			focusedMonitor.Windows.MoveFocus(count)
+			focusedWorkspace.Windows.MoveFocus(count)
 			focusedMonitor.Arrange()
 		}
 
-	case isHex(cmd.Selector):
-		if win, mon := manager.FindWindowString(cmd.Selector); mon != nil && win != nil {
-			mon.Windows.MoveFocusMatch(win)
+	case isWindowId(cmd.Selector):
+		if win, ws, mon := manager.FindWindowString(cmd.Selector); mon != nil && win != nil {
+			ws.Windows.MoveFocusMatch(win)
 			mon.Arrange()
 		}
 
 	case cmd.Selector == "" && selectedWindow != nil:
-		focusedMonitor.Windows.MoveFocusMatch(selectedWindow)
+		for _, win := range selection {
+			focusedWorkspace.Windows.MoveFocusMatch(win)
+		}
+
 		focusedMonitor.Arrange()
 	}
 
-	return nil
+	return nil, nil
+}
+
+// moveWindow runs a move of the focused window of the workspace on win
+// instead, and leaves focus on the window that had it.
+func moveWindow(ws *muon.Workspace, win *muon.Window, move func()) {
+	focused := ws.Focused()
+
+	ws.Windows.FocusMatch(win)
+	move()
+
+	if focused != nil {
+		ws.Windows.FocusMatch(focused)
+	}
 }
 
-// root-window [-focus] [pointer|id|-N|+N|(selected)]
+// moveInDirection swaps a window with its neighbour in the direction, or
+// moves it to the adjacent monitor when it has no neighbour on its own. It
+// returns the monitor the window is on afterwards, or nil if it didn't move.
+// Windows on hidden workspaces have no place to move from.
+func moveInDirection(manager *muon.Manager, win *muon.Window, dir muon.Direction) *muon.Monitor {
+	_, ws, mon := manager.FindWindow(win.Id)
+	if mon == nil || ws != mon.Workspace() {
+		return nil
+	}
+
+	neighbour, target := manager.FindNeighbour(mon, win, dir)
+
+	switch {
+	case target == mon && neighbour != nil:
+		moveWindow(ws, win, func() { ws.Windows.MoveFocusMatch(neighbour) })
+		mon.Arrange()
+
+	case target != nil && target != mon:
+		manager.MoveWindow(win, target.Workspace(), target)
+
+	default:
+		return nil
+	}
+
+	return target
+}
+
+// root-window [-focus] [pointer|id|mark:NAME|-N|+N|(selected)]
 
 type RootWindowCmd struct {
 	Focus    bool
@@ -644,28 +949,30 @@ type RootWindowCmd struct {
 func (cmd RootWindowCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
-	switch {
-	case cmd.Selector == "":
-		focusedMonitor.Windows.NodeMatch(focusedWindow, focusedMonitor.Windows.SwapFront)
-		focusedMonitor.Arrange()
+) (*muon.Window, error) {
+	focusedWorkspace := focusedMonitor.Workspace()
 
-	case selectedWindow != nil:
-		focusedMonitor.Windows.NodeMatch(selectedWindow, focusedMonitor.Windows.SwapFront)
+	switch {
+	case cmd.Selector == "" && selectedWindow != nil:
+		focusedWorkspace.Windows.NodeMatch(selectedWindow, focusedWorkspace.Windows.SwapFront)
 
 		if cmd.Focus {
-			focusedMonitor.Windows.FocusMatch(selectedWindow)
+			focusedWorkspace.Windows.FocusMatch(selectedWindow)
 		}
 
 		focusedMonitor.Arrange()
 
+	case cmd.Selector == "":
+		focusedWorkspace.Windows.NodeMatch(focusedWindow, focusedWorkspace.Windows.SwapFront)
+		focusedMonitor.Arrange()
+
 	case cmd.Selector == "pointer":
-		if win, mon := manager.FindWindowPointer(); mon != nil && win != nil {
-			mon.Windows.NodeMatch(win, mon.Windows.SwapFront)
+		if win, ws, mon := manager.FindWindowPointer(); mon != nil && win != nil {
+			ws.Windows.NodeMatch(win, ws.Windows.SwapFront)
 
 			if cmd.Focus {
 				manager.Monitors.FocusMatch(mon)
-				mon.Windows.FocusMatch(win)
+				ws.Windows.FocusMatch(win)
 			}
 
 			mon.Arrange()
@@ -673,34 +980,35 @@ func (cmd RootWindowCmd) Run(req Request,
 
 	case isCount(cmd.Selector):
 		if count, err := strconv.Atoi(cmd.Selector); err == nil {
-			if win := focusedMonitor.Windows.Select(count); win != nil {
-				focusedMonitor.Windows.NodeMatch(win, focusedMonitor.Windows.SwapFront)
+			if win := focusedWorkspace.Windows.Select(count); win != nil {
+				focusedWorkspace.Windows.NodeMatch(win, focusedWorkspace.Windows.SwapFront)
 
 				if cmd.Focus {
-					focusedMonitor.Windows.FocusMatch(win)
+					focusedWorkspace.Windows.FocusMatch(win)
 				}
 
 				focusedMonitor.Arrange()
 			}
 		}
 
-	case isHex(cmd.Selector):
-		if win, mon := manager.FindWindowString(cmd.Selector); mon != nil && win != nil {
-			mon.Windows.NodeMatch(win, mon.Windows.SwapFront)
+	case isWindowId(cmd.Selector):
+		if win, ws, mon := manager.FindWindowString(cmd.Selector); mon != nil && win != nil {
+			ws.Windows.NodeMatch(win, ws.Windows.SwapFront)
 
 			if cmd.Focus {
 				manager.Monitors.FocusMatch(mon)
-				mon.Windows.FocusMatch(win)
+				mon.FocusWorkspace(ws)
+				ws.Windows.FocusMatch(win)
 			}
 
 			mon.Arrange()
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
-// close-window [pointer|(selected)]
+// close-window [pointer|id|mark:NAME|(selected)]
 
 type CloseWindowCmd struct {
 	Selector string `arg:"positional"`
@@ -709,33 +1017,248 @@ type CloseWindowCmd struct {
 func (cmd CloseWindowCmd) Run(req Request,
 	manager *muon.Manager, focusedMonitor *muon.Monitor,
 	focusedWindow, selectedWindow *muon.Window,
-) *muon.Window {
+) (*muon.Window, error) {
+	var win *muon.Window
+
 	switch {
 	case cmd.Selector == "pointer":
-		if win, mon := manager.FindWindowPointer(); mon != nil && win != nil {
-			if _, ok := win.Protocols[xlib.DeleteWindowAtom]; ok {
-				xlib.ClientMessage(win.Id, uint32(xlib.DeleteWindowAtom), xproto.TimeCurrentTime)
-			} else {
-				xproto.KillClient(xlib.Conn, uint32(win.Id))
-			}
+		win, _, _ = manager.FindWindowPointer()
+
+	case isWindowId(cmd.Selector):
+		if win, _, _ = manager.FindWindowString(cmd.Selector); win == nil {
+			return selectedWindow, fmt.Errorf("window not found: %s", cmd.Selector)
 		}
 
 	case cmd.Selector == "" && selectedWindow != nil:
-		if _, ok := selectedWindow.Protocols[xlib.DeleteWindowAtom]; ok {
-			xlib.ClientMessage(selectedWindow.Id, uint32(xlib.DeleteWindowAtom), xproto.TimeCurrentTime)
-		} else {
-			xproto.KillClient(xlib.Conn, uint32(selectedWindow.Id))
+		for _, win := range selection {
+			closeWindow(win)
 		}
 
+		return nil, nil
+
 	case cmd.Selector == "":
-		if win := focusedMonitor.Focused(); win != nil {
-			if _, ok := win.Protocols[xlib.DeleteWindowAtom]; ok {
-				xlib.ClientMessage(win.Id, uint32(xlib.DeleteWindowAtom), xproto.TimeCurrentTime)
-			} else {
-				xproto.KillClient(xlib.Conn, uint32(win.Id))
+		win = focusedMonitor.Focused()
+	}
+
+	if win != nil {
+		closeWindow(win)
+	}
+
+	return nil, nil
+}
+
+func closeWindow(win *muon.Window) {
+	if _, ok := win.Protocols[xlib.DeleteWindowAtom]; ok {
+		xlib.ClientMessage(win.Id, uint32(xlib.DeleteWindowAtom), xproto.TimeCurrentTime)
+	} else {
+		xproto.KillClient(xlib.Conn, uint32(win.Id))
+	}
+}
+
+// mark-window [-window pointer|id|mark:NAME] [name]
+
+type MarkWindowCmd struct {
+	Window string
+	Name   string `arg:"positional"`
+}
+
+func (cmd MarkWindowCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	win, err := targetWindow(manager, cmd.Window, focusedWindow, selectedWindow)
+	if err != nil || win == nil {
+		return selectedWindow, err
+	}
+
+	switch {
+	case cmd.Name == "":
+		if len(win.Marks) != 0 {
+			fmt.Fprintln(req, strings.Join(win.Marks, " "))
+		}
+
+	case strings.ContainsAny(cmd.Name, " \t"):
+		return selectedWindow, fmt.Errorf("invalid mark: %s", cmd.Name)
+
+	default:
+		manager.Mark(win, cmd.Name)
+	}
+
+	return selectedWindow, nil
+}
+
+// unmark-window name...
+
+type UnmarkWindowCmd struct {
+	Names []string `arg:"positional"`
+}
+
+func (cmd UnmarkWindowCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	for _, name := range cmd.Names {
+		if !manager.Unmark(name) {
+			return selectedWindow, fmt.Errorf("mark not found: %s", name)
+		}
+	}
+
+	return selectedWindow, nil
+}
+
+// rule add [-instance name] [-class name] [-title regexp] [-role role] [-type type]
+//          [-monitor name|index|pointer|focused] [-position root|end]
+//          [-floating] [-fullscreen] [-focus] [-unmanaged]
+// rule remove index|all
+// rule list
+
+type RuleCmd struct {
+	Add    *RuleAddCmd    `arg:"subcommand:add"`
+	Remove *RuleRemoveCmd `arg:"subcommand:remove"`
+	List   *RuleListCmd   `arg:"subcommand:list"`
+}
+
+type RuleAddCmd struct {
+	Instance   string
+	Class      string
+	Title      string
+	Role       string
+	Type       string
+	Monitor    string
+	Position   string
+	Floating   bool
+	Fullscreen bool
+	Focus      bool
+	Unmanaged  bool
+}
+
+type RuleRemoveCmd struct {
+	Index string `arg:"positional"`
+}
+
+type RuleListCmd struct {
+}
+
+func (cmd RuleCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	switch {
+	case cmd.Add != nil:
+		rule := &muon.Rule{
+			Instance:   cmd.Add.Instance,
+			Class:      cmd.Add.Class,
+			Role:       cmd.Add.Role,
+			Type:       strings.ToLower(cmd.Add.Type),
+			Monitor:    cmd.Add.Monitor,
+			Position:   cmd.Add.Position,
+			Floating:   cmd.Add.Floating,
+			Fullscreen: cmd.Add.Fullscreen,
+			Focus:      cmd.Add.Focus,
+			Unmanaged:  cmd.Add.Unmanaged,
+		}
+
+		if rule.Position != "" && rule.Position != "root" && rule.Position != "end" {
+			return selectedWindow, fmt.Errorf("invalid position: %s", rule.Position)
+		}
+
+		if cmd.Add.Title != "" {
+			title, err := regexp.Compile(cmd.Add.Title)
+			if err != nil {
+				return selectedWindow, err
 			}
+
+			rule.Title = title
+		}
+
+		manager.Rules = append(manager.Rules, rule)
+
+	case cmd.Remove != nil && cmd.Remove.Index == "all":
+		manager.Rules = nil
+
+	case cmd.Remove != nil:
+		if index, err := strconv.Atoi(cmd.Remove.Index); err == nil && index >= 1 && index <= len(manager.Rules) {
+			manager.Rules = append(manager.Rules[:index-1], manager.Rules[index:]...)
+		} else {
+			return selectedWindow, fmt.Errorf("rule not found: %s", cmd.Remove.Index)
+		}
+
+	default:
+		for i, rule := range manager.Rules {
+			fmt.Fprintln(req, i+1, rule)
 		}
 	}
 
-	return nil
+	return selectedWindow, nil
+}
+
+// reload
+
+type ReloadCmd struct {
+}
+
+func (cmd ReloadCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	return selectedWindow, loadConfig(manager)
+}
+
+// subscribe [topic...]
+//
+// The connection belongs to the subscription afterwards, so subscribe has to
+// be the last command of a request, and can't run from the config file.
+
+type SubscribeCmd struct {
+	Topics []string `arg:"positional"`
+}
+
+func (cmd SubscribeCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	switch {
+	case req.Conn == nil:
+		return selectedWindow, fmt.Errorf("subscribe needs a connection")
+	case !req.last:
+		return selectedWindow, fmt.Errorf("subscribe has to be the last command")
+	}
+
+	return selectedWindow, subscribe(manager, req, cmd.Topics)
+}
+
+// query [-monitor name|index|pointer] windows|monitors|layouts|tree|commands
+
+type QueryCmd struct {
+	MonitorTarget
+	Kind string `arg:"positional"`
+}
+
+func (cmd QueryCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	monitors := manager.Monitors.All()
+	if cmd.Monitor != "" {
+		monitors = []*muon.Monitor{focusedMonitor}
+	}
+
+	var value interface{}
+
+	switch cmd.Kind {
+	case "windows":
+		value = queryWindows(manager, monitors)
+	case "monitors":
+		value = queryMonitors(manager, monitors)
+	case "layouts":
+		value = newWorkspaceInfo(focusedMonitor, focusedMonitor.Workspace()).Layouts
+	case "tree":
+		value = queryTree(manager, monitors)
+	case "commands":
+		value = queryCommands()
+	default:
+		return selectedWindow, fmt.Errorf("unknown query: %s", cmd.Kind)
+	}
+
+	return selectedWindow, req.Reply(value)
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"yuki.no/muon/muon"
+)
+
+func TestPrepareCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want Command
+	}{
+		{"send-to-workspace -1", &SendToWorkspaceCmd{Workspace: "-1"}},
+		{"send-to-workspace -1 -follow", &SendToWorkspaceCmd{Workspace: "-1", Follow: true}},
+		{"send-to-workspace -follow +1", &SendToWorkspaceCmd{Workspace: "+1", Follow: true}},
+		{"send-window -1 -follow", &SendWindowCmd{Monitor: "-1", Follow: true}},
+		{"send-window -window 0x01 -1 -follow", &SendWindowCmd{Window: "0x01", Monitor: "-1", Follow: true}},
+		{"root-window -1 -focus", &RootWindowCmd{Selector: "-1", Focus: true}},
+		{"focus-window history -1", &FocusWindowCmd{Selector: "history", Count: "-1"}},
+		{"padding -default left -10", &PaddingCmd{Default: true, Direction: "left", Size: "-10"}},
+		{"incr -monitor 1 ratio -0.05", &IncrCmd{PropertyTarget: PropertyTarget{MonitorTarget: MonitorTarget{Monitor: "1"}}, Name: "ratio", Delta: "-0.05"}},
+		{"layouts -default vertical", &LayoutsCmd{Default: true, Names: []string{"vertical"}}},
+	}
+
+	for _, test := range tests {
+		fields := strings.Fields(test.line)
+
+		cmd, err := prepareCommand(Request{Command: fields[0], Args: fields[1:]})
+		if err != nil {
+			t.Errorf("%s: %s", test.line, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(cmd, test.want) {
+			t.Errorf("%s: parsed %+v, want %+v", test.line, cmd, test.want)
+		}
+	}
+
+	_, err := prepareCommand(Request{Command: "send-to-workspace", Args: []string{"-1", "-2"}})
+	if err == nil || strings.Contains(err.Error(), countMark) {
+		t.Errorf("send-to-workspace -1 -2: error = %v", err)
+	}
+}
+
+func TestRootWindowSelector(t *testing.T) {
+	defer func() { selection = nil }()
+
+	manager := newTestManager("DP-1")
+	ws := manager.Focused().Workspace()
+
+	windows := []*muon.Window{{Id: 1}, {Id: 2}, {Id: 3}}
+	for _, win := range windows {
+		ws.Windows.Insert(win)
+	}
+
+	tests := []struct {
+		args []string
+		want *muon.Window
+	}{
+		{[]string{"0x2"}, windows[1]},
+		{nil, windows[2]},
+	}
+
+	for _, test := range tests {
+		selection = []*muon.Window{windows[2]}
+
+		req := Request{Writer: nopConn{}, Command: "root-window", Args: test.args}
+		if err := execute(manager, req, manager.Focused(), ws.Focused()); err != nil {
+			t.Fatal(err)
+		}
+
+		if root := ws.Windows.All()[0]; root != test.want {
+			t.Errorf("root-window %v: root = 0x%x, want 0x%x", test.args, root.Id, test.want.Id)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"yuki.no/muon/muon"
+)
+
+// The configuration file holds one command per line, using the same syntax as
+// muctl. Lines before the first section run once and should set defaults, for
+// example `border-width -default 4`. Lines in a [monitor NAME] section run
+// on the monitor with that output name. Workspace settings like ratio or
+// select-layout run there for every workspace, and all other lines once, so
+// workspace settings can't be chained with other commands.
+//
+// Reloading runs the file again on top of the current state. Workspaces keep
+// their layout, ratio, root count and mirroring unless a line sets them, and
+// monitors keep the settings changed on them since they started. All other
+// monitor settings follow the new defaults.
+//
+//	normal-border #3f3e3b
+//	ratio -default 0.6
+//	rule add -class Pavucontrol -floating
+//
+//	[monitor eDP-1]
+//	padding top 24
+
+type ConfigLine struct {
+	Number int
+	Fields []string
+}
+
+type Config struct {
+	Path     string
+	Global   []ConfigLine
+	Monitors map[string][]ConfigLine
+}
+
+type configWriter struct {
+	line ConfigLine
+}
+
+func (w configWriter) Write(p []byte) (int, error) {
+	zap.S().Infow("config", "line", w.line.Number, "output", strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+func configPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+
+	return filepath.Join(dir, "muon", "config")
+}
+
+func readConfig(path string) (*Config, error) {
+	config := &Config{Path: path, Monitors: make(map[string][]ConfigLine)}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var (
+		monitor string
+		scanner = bufio.NewScanner(file)
+	)
+
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			fields := strings.Fields(strings.Trim(line, "[]"))
+			if len(fields) != 2 || fields[0] != "monitor" {
+				return nil, fmt.Errorf("%s:%d: invalid section: %s", path, number, line)
+			}
+
+			monitor = fields[1]
+			continue
+		}
+
+		configLine := ConfigLine{Number: number, Fields: strings.Fields(line)}
+		if monitor == "" {
+			config.Global = append(config.Global, configLine)
+		} else {
+			config.Monitors[monitor] = append(config.Monitors[monitor], configLine)
+		}
+	}
+
+	return config, scanner.Err()
+}
+
+// run executes the lines without a selection, and leaves the selection as it
+// was.
+func (config *Config) run(manager *muon.Manager, mon *muon.Monitor, lines []ConfigLine) {
+	previous := selection
+	selection = nil
+
+	defer func() { selection = previous }()
+
+	for _, line := range lines {
+		req := Request{Writer: configWriter{line}, Command: line.Fields[0], Args: line.Fields[1:]}
+
+		if err := executeChain(manager, req, mon, mon.Focused()); err != nil {
+			zap.S().Warnw("config", "path", config.Path, "line", line.Number, "error", err)
+		}
+	}
+}
+
+func (config *Config) runMonitor(manager *muon.Manager, mon *muon.Monitor) {
+	lines := config.Monitors[mon.Name]
+	if len(lines) == 0 {
+		return
+	}
+
+	var once, each []ConfigLine
+	for _, line := range lines {
+		switch scoped := countScoped(line.Fields); scoped {
+		case 0:
+			once = append(once, line)
+		case len(parseChain(line.Fields)):
+			each = append(each, line)
+		default:
+			zap.S().Warnw("config", "path", config.Path, "line", line.Number,
+				"error", "workspace settings chained with other commands")
+		}
+	}
+
+	config.run(manager, mon, once)
+
+	focused := mon.Workspace()
+	for _, ws := range mon.Workspaces.All() {
+		mon.Workspaces.FocusMatch(ws)
+		config.run(manager, mon, each)
+	}
+
+	mon.Workspaces.FocusMatch(focused)
+}
+
+// countScoped counts the steps of a line that change workspace settings.
+func countScoped(fields []string) int {
+	var count int
+	for _, step := range parseChain(fields) {
+		if workspaceScoped(step.Fields) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func configureMonitor(manager *muon.Manager, mon *muon.Monitor) error {
+	config, err := readConfig(configPath())
+	if err != nil {
+		return err
+	}
+
+	config.runMonitor(manager, mon)
+	return nil
+}
+
+func loadConfig(manager *muon.Manager) error {
+	config, err := readConfig(configPath())
+	if err != nil {
+		return err
+	}
+
+	zap.S().Infow("config", "path", config.Path)
+
+	manager.Reset()
+	aliases = make(map[string][]string)
+
+	if mon := manager.Focused(); mon != nil {
+		config.run(manager, mon, config.Global)
+	}
+
+	for _, mon := range manager.Monitors.All() {
+		mon.Follow(manager)
+		config.runMonitor(manager, mon)
+	}
+
+	for _, mon := range manager.Monitors.All() {
+		mon.Arrange()
+	}
+
+	manager.Repaint(selection)
+
+	return nil
+}
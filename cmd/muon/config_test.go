@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yuki.no/muon/muon"
+	"yuki.no/muon/rect"
+)
+
+// newTestManager sets up held monitors without colors, so that commands can
+// run without an X server as long as they don't touch windows.
+func newTestManager(names ...string) *muon.Manager {
+	manager := &muon.Manager{WorkspaceCount: 3, Ratio: 0.65, Monitors: muon.NewMonitorList()}
+
+	for i, name := range names {
+		manager.Monitors.Insert(muon.NewMonitor(manager, name, rect.New(i*1000, 0, 1000, 1000)))
+	}
+
+	manager.Hold()
+	return manager
+}
+
+func writeConfig(t *testing.T, dir, content string) *Config {
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := readConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestConfigSubscribe(t *testing.T) {
+	defer func() { subscriptions, aliases = nil, make(map[string][]string) }()
+
+	dir, err := ioutil.TempDir("", "muon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	manager := newTestManager("DP-1")
+	config := writeConfig(t, dir, "subscribe\nalias watch = subscribe focus\nwatch\n")
+
+	config.run(manager, manager.Focused(), config.Global)
+
+	if len(subscriptions) != 0 {
+		t.Errorf("config added %d subscriptions", len(subscriptions))
+	}
+}
+
+func TestConfigMonitor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "muon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	manager := newTestManager("DP-1")
+	mon := manager.Focused()
+	config := writeConfig(t, dir, `[monitor DP-1]
+rule add -class Pavucontrol -floating
+ratio 0.5
+root-count 1 && mirror-layout true
+ratio 0.4 ; rule add -class Firefox
+`)
+
+	config.runMonitor(manager, mon)
+
+	if len(manager.Rules) != 1 {
+		t.Errorf("monitor section added %d rules, want 1", len(manager.Rules))
+	}
+
+	for _, ws := range mon.Workspaces.All() {
+		if ws.Ratio != 0.5 || !ws.Mirrored {
+			t.Errorf("workspace %s: ratio = %v, mirrored = %v, want 0.5 true", ws, ws.Ratio, ws.Mirrored)
+		}
+	}
+}
+
+type nopConn struct{}
+
+func (nopConn) Write(p []byte) (int, error) { return len(p), nil }
+func (nopConn) Close() error                { return nil }
+
+func TestSubscribeLast(t *testing.T) {
+	defer func() { subscriptions = nil }()
+
+	manager := newTestManager("DP-1")
+	mon := manager.Focused()
+
+	tests := []struct {
+		fields     []string
+		subscribed bool
+	}{
+		{[]string{"subscribe", "focus"}, true},
+		{[]string{"help", "&&", "subscribe", "focus"}, true},
+		{[]string{"subscribe", "focus", "&&", "help"}, false},
+		{[]string{"subscribe", "focus", ";", "help"}, false},
+		{[]string{"subscribe", "||", "help"}, false},
+	}
+
+	for _, test := range tests {
+		subscriptions = nil
+
+		req := Request{Writer: nopConn{}, Conn: nopConn{}, Command: test.fields[0], Args: test.fields[1:], last: true}
+		executeChain(manager, req, mon, nil)
+
+		if subscribed := len(subscriptions) != 0; subscribed != test.subscribed {
+			t.Errorf("%v: subscribed = %v, want %v", test.fields, subscribed, test.subscribed)
+		}
+	}
+
+	// Only the last command of a batch can subscribe.
+	subscriptions = nil
+	batch := Request{Conn: nopConn{}, Version: ProtocolVersion, Batch: []Request{
+		{Conn: nopConn{}, Command: "subscribe", Version: ProtocolVersion},
+		{Conn: nopConn{}, Command: "help", Version: ProtocolVersion},
+	}}
+
+	runBatch(manager, batch, &Response{Version: ProtocolVersion})
+	if len(subscriptions) != 0 {
+		t.Errorf("batch subscribed before its last command")
+	}
+}
@@ -12,28 +12,39 @@ import (
 	"yuki.no/muon/xlib"
 )
 
+// timestamp is the server time of the last event that had one. WM_TAKE_FOCUS
+// messages carry it, since ICCCM doesn't allow CurrentTime in them, and
+// commands come without a timestamp of their own.
+var timestamp xproto.Timestamp
+
 func handleEvent(manager *muon.Manager, base xgb.Event, previousWindow **muon.Window) {
 	switch event := base.(type) {
 	case randr.ScreenChangeNotifyEvent:
 		zap.S().Infow("event", "type", "ScreenChangeNotify")
-		// manager.Setup()
+		updateMonitors(manager)
 
 	case xproto.ConfigureNotifyEvent:
 		zap.S().Infow("event", "type", "ConfigureNotify", "window", fmt.Sprintf("0x%08x", event.Window))
 
 		if event.Window == xlib.RootWindow {
-			// manager.Setup()
+			updateMonitors(manager)
 			return
 		}
 
-		if win, _ := manager.FindWindow(event.Window); win != nil {
-			win.Name = xlib.GetString(event.Window, xlib.NameAtom)
+		// Raises and other restacks are reported as ConfigureNotify too.
+		if win, _, _ := manager.FindWindow(event.Window); win != nil {
+			win.UpdateName()
+			manager.Restacked()
 		}
 
 	case xproto.ConfigureRequestEvent:
 		zap.S().Infow("event", "type", "ConfigureRequest", "window", fmt.Sprintf("0x%08x", event.Window))
 
-		if win, mon := manager.FindWindow(event.Window); mon != nil && win != nil {
+		if win, _, mon := manager.FindWindow(event.Window); mon != nil && win != nil && win.Fullscreen {
+			xlib.ConfigureFromGeometry(event.Window, 0, mon.Geometry)
+		} else if mon != nil && win != nil && win.Floating {
+			win.Configure(event)
+		} else if mon != nil && win != nil {
 			xlib.ConfigureFromGeometry(event.Window, mon.BorderWidth, win.Geometry)
 		} else {
 			xlib.ConfigureFromRequest(event)
@@ -42,14 +53,100 @@ func handleEvent(manager *muon.Manager, base xgb.Event, previousWindow **muon.Wi
 	case xproto.UnmapNotifyEvent:
 		zap.S().Infow("event", "type", "UnmapNotify", "window", fmt.Sprintf("0x%08x", event.Window))
 
-		if win, mon := manager.FindWindow(event.Window); mon != nil || win != nil {
-			mon.Windows.RemoveMatch(win)
+		if manager.RemoveDock(event.Window) {
+			return
+		}
+
+		if win, ws, mon := manager.FindWindow(event.Window); mon != nil && win != nil {
+			if win.Hidden() {
+				return
+			}
+
+			manager.RemoveWindow(win, ws)
 			mon.Arrange()
 			if *previousWindow == win {
 				*previousWindow = nil
 			}
 		}
 
+	case xproto.DestroyNotifyEvent:
+		zap.S().Infow("event", "type", "DestroyNotify", "window", fmt.Sprintf("0x%08x", event.Window))
+
+		if manager.RemoveDock(event.Window) {
+			return
+		}
+
+		if win, ws, mon := manager.FindWindow(event.Window); mon != nil && win != nil {
+			manager.RemoveWindow(win, ws)
+			if ws == mon.Workspace() {
+				mon.Arrange()
+			}
+			if *previousWindow == win {
+				*previousWindow = nil
+			}
+		}
+
+	case xproto.PropertyNotifyEvent:
+		timestamp = event.Time
+
+		if win, ws, mon := manager.FindWindow(event.Window); mon != nil && win != nil {
+			switch event.Atom {
+			case xproto.AtomWmNormalHints:
+				win.UpdateHints()
+				if ws == mon.Workspace() && !win.Floating {
+					mon.Arrange()
+				}
+
+			case xproto.AtomWmName, xlib.NetNameAtom:
+				win.UpdateName()
+
+			case xproto.AtomWmHints:
+				urgent := win.Urgent
+				win.UpdateWMHints()
+
+				if win.Urgent != urgent && !isSelected(win) {
+					xlib.SetBorderColor(win.Id, unselectedBorder(manager, win).Value)
+				}
+			}
+
+			return
+		}
+
+		if dock := manager.FindDock(event.Window); dock != nil {
+			if event.Atom == xlib.NetStrutAtom || event.Atom == xlib.NetStrutPartialAtom {
+				dock.UpdateStrut()
+				manager.UpdateStruts()
+			}
+		}
+
+	case xproto.ClientMessageEvent:
+		zap.S().Infow("event", "type", "ClientMessage", "window", fmt.Sprintf("0x%08x", event.Window))
+
+		win, ws, mon := manager.FindWindow(event.Window)
+		if mon == nil || win == nil {
+			return
+		}
+
+		if event.Type == xlib.NetStateAtom {
+			data := event.Data.Data32
+			for _, atom := range data[1:3] {
+				switch xproto.Atom(atom) {
+				case xlib.NetStateFullscreenAtom:
+					win.SetFullscreen(netState(data[0], win.Fullscreen))
+
+					if ws == mon.Workspace() {
+						mon.Arrange()
+					}
+
+				case xlib.NetStateAttentionAtom:
+					if win != *previousWindow {
+						win.SetUrgent(netState(data[0], win.Urgent))
+						xlib.SetBorderColor(win.Id, manager.Border(win).Value)
+					}
+				}
+			}
+		}
+
 	case xproto.MapRequestEvent:
 		zap.S().Infow("event", "type", "MapRequest", "window", fmt.Sprintf("0x%08x", event.Window))
 
@@ -58,30 +155,117 @@ func handleEvent(manager *muon.Manager, base xgb.Event, previousWindow **muon.Wi
 			return
 		}
 
-		if win, _ := manager.FindWindow(event.Window); win != nil {
+		if win, _, _ := manager.FindWindow(event.Window); win != nil {
 			return
 		}
 
 		window := muon.NewWindow(manager, event.Window)
-		xlib.SetBorderColor(window.Id, manager.NormalBorder.Value)
+		if window.Type == "dock" {
+			manager.AddDock(window.Id)
+			xlib.MapWindow(window.Id)
+			return
+		}
+
+		rule := manager.MatchRules(window)
+		if rule.Unmanaged {
+			xlib.MapWindow(window.Id)
+			return
+		}
+
+		xlib.SetBorderColor(window.Id, manager.Border(window).Value)
 		defer xlib.MapWindow(window.Id)
 
-		if transient := xlib.GetWindow(event.Window, xlib.TransientForAtom); transient != 0 {
-			if win, mon := manager.FindWindow(transient); mon != nil && win != nil {
-				mon.Windows.InsertAfterFocus(window)
-				if !mon.Fullscreen && mon.Focused() == win {
-					mon.Windows.FocusMatch(window)
-				}
-				mon.Arrange()
-				break
-			}
+		manageWindow(manager, window, rule)
+	}
+
+	return
+}
+
+func netState(action uint32, current bool) bool {
+	switch action {
+	case xlib.NetStateRemove:
+		return false
+	case xlib.NetStateAdd:
+		return true
+	case xlib.NetStateToggle:
+		return !current
+	}
+
+	return current
+}
+
+func manageWindow(manager *muon.Manager, window *muon.Window, rule muon.Rule) {
+	var (
+		mon    = manager.Focused()
+		parent *muon.Window
+	)
+
+	if transient := xlib.GetWindow(window.Id, xlib.TransientForAtom); transient != 0 {
+		if win, ws, owner := manager.FindWindow(transient); owner != nil && win != nil && ws == owner.Workspace() {
+			mon, parent = owner, win
 		}
+	}
 
-		if mon := manager.Focused(); mon != nil {
-			mon.Windows.Insert(window)
-			mon.Arrange()
+	if rule.Monitor != "" {
+		if target := manager.FindMonitorString(rule.Monitor); target != nil {
+			mon, parent = target, nil
 		}
 	}
 
-	return
+	if mon == nil {
+		return
+	}
+
+	ws := mon.Workspace()
+
+	if rule.Floating || (manager.FloatFixed && window.Hints.Fixed()) {
+		window.Float(true)
+
+		if !mon.Geometry.Contains(window.Geometry.X, window.Geometry.Y) {
+			window.Geometry = mon.Geometry.Center(window.Geometry.W, window.Geometry.H)
+		}
+	}
+
+	switch {
+	case parent != nil:
+		ws.Windows.InsertAfterFocus(window)
+		if !ws.Fullscreen && ws.Focused() == parent {
+			ws.Windows.FocusMatch(window)
+		}
+
+	case rule.Position == "root":
+		ws.Windows.InsertFront(window)
+
+	default:
+		ws.Windows.Insert(window)
+	}
+
+	if rule.Focus {
+		manager.Monitors.FocusMatch(mon)
+		ws.Windows.FocusMatch(window)
+	}
+
+	if rule.Fullscreen {
+		window.Fullscreen = true
+	}
+
+	window.UpdateState()
+
+	mon.Arrange()
+}
+
+func updateMonitors(manager *muon.Manager) {
+	added, err := manager.UpdateMonitors()
+	if err != nil {
+		zap.S().Error(err)
+		return
+	}
+
+	for _, mon := range added {
+		if err := configureMonitor(manager, mon); err != nil {
+			zap.S().Error(err)
+		}
+
+		mon.Arrange()
+	}
 }
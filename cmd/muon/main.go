@@ -1,12 +1,11 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"net"
 	"os"
 	"os/signal"
-	"strings"
+	"syscall"
 	"time"
 
 	"github.com/BurntSushi/xgb"
@@ -20,6 +19,7 @@ func main() {
 	var (
 		ctx, cancel    = context.WithCancel(context.Background())
 		signalChannel  = make(chan os.Signal, 1)
+		reloadChannel  = make(chan os.Signal, 1)
 		eventChannel   = make(chan xgb.Event)
 		errorChannel   = make(chan error)
 		commandChannel = make(chan Request)
@@ -27,6 +27,11 @@ func main() {
 
 	defer cancel()
 	signal.Notify(signalChannel, os.Interrupt)
+	signal.Notify(reloadChannel, syscall.SIGHUP)
+
+	if err := xlib.Connect(); err != nil {
+		panic(err)
+	}
 
 	defer os.Remove("/tmp/muon")
 	os.Remove("/tmp/muon")
@@ -48,6 +53,12 @@ func main() {
 		panic(err)
 	}
 
+	defer manager.ShowWindows()
+
+	if err := loadConfig(manager); err != nil {
+		zap.S().Error(err)
+	}
+
 	go func() {
 		for {
 			conn, err := socket.Accept()
@@ -56,17 +67,7 @@ func main() {
 				continue
 			}
 
-			defer conn.Close()
-			reader := bufio.NewReader(conn)
-			command, err := reader.ReadString('\n')
-			if err != nil {
-				errorChannel <- err
-				continue
-			}
-
-			if fields := strings.Fields(command); len(fields) != 0 {
-				commandChannel <- Request{Conn: conn, Command: fields[0], Args: fields[1:]}
-			}
+			go serve(conn, commandChannel, errorChannel)
 		}
 	}()
 
@@ -82,16 +83,13 @@ func main() {
 		}
 	}()
 
-	var (
-		clearSelection = time.NewTimer(0)
-		selectedWindow *muon.Window
-	)
+	selectionTimer := time.NewTimer(0)
 
 	for {
 		var (
 			previousMonitor   = manager.Focused()
 			previousWindow    = previousMonitor.Focused()
-			previousSelection = selectedWindow
+			previousSelection = selection
 		)
 
 		select {
@@ -101,42 +99,29 @@ func main() {
 		case <-signalChannel:
 			return
 
+		case <-reloadChannel:
+			if err := loadConfig(manager); err != nil {
+				zap.S().Error(err)
+			}
+
 		case err := <-errorChannel:
 			zap.S().Error(err)
 
-		case <-clearSelection.C:
-			if selectedWindow != nil {
-				xlib.SetBorderColor(selectedWindow.Id, manager.NormalBorder.Value)
-				selectedWindow = nil
-			}
+		case <-selectionTimer.C:
+			clearSelection(manager)
 
 		case request := <-commandChannel:
-			selectedWindow = runCommand(manager, request, previousMonitor, previousWindow, previousSelection)
-			resetSelection(manager, clearSelection, selectedWindow, previousSelection)
+			runCommand(manager, request, previousMonitor, previousWindow)
+			resetSelection(manager, selectionTimer, previousSelection)
 			resetFocus(manager, previousWindow)
 
 		case event := <-eventChannel:
 			handleEvent(manager, event, &previousWindow)
+			pruneSelection(manager)
 			resetFocus(manager, previousWindow)
 		}
-	}
-}
-
-func resetSelection(manager *muon.Manager, clearSelection *time.Timer, selectedWindow, previousSelection *muon.Window) {
-	if selectedWindow == previousSelection {
-		clearSelection.Reset(time.Second)
-		return
-	}
-
-	if selectedWindow != nil {
-		zap.S().Infow("select", "window", selectedWindow)
 
-		xlib.SetBorderColor(selectedWindow.Id, manager.SelectedBorder.Value)
-		clearSelection.Reset(time.Second)
-	}
-
-	if previousSelection != nil {
-		xlib.SetBorderColor(previousSelection.Id, manager.NormalBorder.Value)
+		publish(manager)
 	}
 }
 
@@ -144,16 +129,27 @@ func resetFocus(manager *muon.Manager, previousWindow *muon.Window) {
 	if mon := manager.Focused(); mon != nil {
 		if win := mon.Focused(); win != nil {
 			if previousWindow != win {
-				xlib.SetBorderColor(win.Id, manager.FocusedBorder.Value)
-				xlib.Raise(win.Id)
-				xlib.SetFocus(win.Id)
+				if !isSelected(win) {
+					xlib.SetBorderColor(win.Id, manager.FocusedBorder.Value)
+				}
+
+				mon.Restack()
+				win.Focus(timestamp)
+				manager.Remember(win)
 
-				zap.S().Infow("focus", "window", win)
+				zap.S().Infow("focus", "window", win, "input", win.Input)
 
-				if previousWindow != nil {
-					xlib.SetBorderColor(previousWindow.Id, manager.NormalBorder.Value)
+				if previousWindow != nil && !isSelected(previousWindow) {
+					xlib.SetBorderColor(previousWindow.Id, manager.Border(previousWindow).Value)
 				}
 			}
+
+			if win.Urgent {
+				win.SetUrgent(false)
+			}
 		}
 	}
+
+	manager.UpdateClientList()
+	manager.UpdateActiveWindow()
 }
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"yuki.no/muon/muon"
+)
+
+// Settings are properties, which can be reached through get, set, toggle and
+// incr, or through a command named after the property:
+//
+//	selected-border [color]
+//	normal-border [color]
+//	focused-border [color]
+//	urgent-border [color]
+//	float-fixed [toggle|true|false]
+//	border-width [-monitor name|index|pointer] [-default] [-N|+N|size|default]
+//	window-gap [-monitor name|index|pointer] [-default] [-N|+N|size|default]
+//	hint-alignment [-monitor name|index|pointer] [-default] [center|top-left|default]
+//	root-count [-monitor name|index|pointer] [-N|+N|count]
+//	ratio [-monitor name|index|pointer] [-default] [-N|+N|size]
+//	fullscreen [-monitor name|index|pointer] [false|true|toggle]
+//	mirror-layout [-monitor name|index|pointer] [false|true|toggle]
+//	float-window [-window pointer|id|mark:NAME] [toggle|true|false]
+//
+// Setting a monitor property to default makes the monitor follow the manager
+// default again. Changing a window property without -window changes it on
+// every selected window.
+
+type PropertyTarget struct {
+	MonitorTarget
+	Default bool
+	Window  string
+}
+
+func (target PropertyTarget) Target(manager *muon.Manager, focusedMonitor *muon.Monitor, focusedWindow, selectedWindow *muon.Window) (muon.Target, error) {
+	win, err := targetWindow(manager, target.Window, focusedWindow, selectedWindow)
+
+	return muon.Target{
+		Manager:  manager,
+		Monitor:  focusedMonitor,
+		Window:   win,
+		Selected: selection,
+		Default:  target.Default,
+	}, err
+}
+
+// workspaceScoped reports whether the named property is changed on the
+// workspace rather than on its default.
+func (target PropertyTarget) workspaceScoped(name string) bool {
+	prop := muon.FindProperty(name)
+	return prop != nil && prop.Scope == muon.WorkspaceScope && !target.Default
+}
+
+// windowTarget points the target at the monitor of its window.
+func windowTarget(manager *muon.Manager, prop *muon.Property, t muon.Target) muon.Target {
+	if prop.Scope == muon.WindowScope && t.Window != nil {
+		_, _, t.Monitor = manager.FindWindow(t.Window.Id)
+		if t.Monitor == nil {
+			t.Window = nil
+		}
+	}
+
+	return t
+}
+
+// targets gives a target for every selected window when a window property
+// is changed without -window.
+func (target PropertyTarget) targets(manager *muon.Manager, prop *muon.Property, t muon.Target) []muon.Target {
+	if prop.Scope != muon.WindowScope || target.Window != "" || len(t.Selected) == 0 {
+		return []muon.Target{windowTarget(manager, prop, t)}
+	}
+
+	var targets []muon.Target
+	for _, win := range t.Selected {
+		t.Window = win
+		targets = append(targets, windowTarget(manager, prop, t))
+	}
+
+	return targets
+}
+
+// changeAll makes the same change to every target, and stops at the first
+// error.
+func changeAll(targets []muon.Target, change func(muon.Target) error) error {
+	for _, t := range targets {
+		if err := change(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findProperty(name string) (*muon.Property, error) {
+	if prop := muon.FindProperty(name); prop != nil {
+		return prop, nil
+	}
+
+	return nil, fmt.Errorf("property not found: %s", name)
+}
+
+// changeProperty prints the property without a value, and otherwise changes
+// it the way the value reads.
+func changeProperty(req Request, prop *muon.Property, t muon.Target, value string) error {
+	switch {
+	case value == "":
+		current, err := prop.Get(t)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(req, current)
+		return nil
+
+	case value == "toggle":
+		return prop.Toggle(t)
+
+	case value == "default" && prop.Scope == muon.MonitorScope:
+		return prop.Reset(t)
+
+	case isCount(value) && (prop.Type == muon.IntProperty || prop.Type == muon.FloatProperty):
+		return prop.Incr(t, value)
+	}
+
+	return prop.Set(t, value)
+}
+
+// PropertyCmd is the command named after a property.
+type PropertyCmd struct {
+	PropertyTarget
+	Value string `arg:"positional"`
+	name  string `arg:"-"`
+}
+
+func (cmd PropertyCmd) WorkspaceScoped() bool {
+	return cmd.Value != "" && cmd.workspaceScoped(cmd.name)
+}
+
+func (cmd PropertyCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	prop, err := findProperty(cmd.name)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	t, err := cmd.Target(manager, focusedMonitor, focusedWindow, selectedWindow)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	if cmd.Value == "" {
+		return selectedWindow, changeProperty(req, prop, windowTarget(manager, prop, t), "")
+	}
+
+	return selectedWindow, changeAll(cmd.targets(manager, prop, t), func(t muon.Target) error {
+		return changeProperty(req, prop, t, cmd.Value)
+	})
+}
+
+// get [-monitor name|index|pointer] [-default] [-window pointer|id|mark:NAME] [property]
+
+type GetCmd struct {
+	PropertyTarget
+	Name string `arg:"positional"`
+}
+
+func (cmd GetCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	t, err := cmd.Target(manager, focusedMonitor, focusedWindow, selectedWindow)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	if cmd.Name != "" {
+		prop, err := findProperty(cmd.Name)
+		if err != nil {
+			return selectedWindow, err
+		}
+
+		return selectedWindow, changeProperty(req, prop, windowTarget(manager, prop, t), "")
+	}
+
+	w := tabwriter.NewWriter(req, 0, 8, 2, ' ', 0)
+	for _, prop := range muon.Properties() {
+		value, err := prop.Get(windowTarget(manager, prop, t))
+		if err != nil {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", prop.Name, prop.Scope, prop.Type, value)
+	}
+
+	return selectedWindow, w.Flush()
+}
+
+// set [-monitor name|index|pointer] [-default] [-window pointer|id|mark:NAME] property value|default
+
+type SetCmd struct {
+	PropertyTarget
+	Name  string `arg:"positional"`
+	Value string `arg:"positional"`
+}
+
+func (cmd SetCmd) WorkspaceScoped() bool {
+	return cmd.workspaceScoped(cmd.Name)
+}
+
+func (cmd SetCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	prop, err := findProperty(cmd.Name)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	t, err := cmd.Target(manager, focusedMonitor, focusedWindow, selectedWindow)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	switch {
+	case cmd.Value == "":
+		return selectedWindow, fmt.Errorf("usage: set property value")
+	case cmd.Value == "default" && prop.Scope == muon.MonitorScope:
+		return selectedWindow, prop.Reset(t)
+	}
+
+	return selectedWindow, changeAll(cmd.targets(manager, prop, t), func(t muon.Target) error {
+		return prop.Set(t, cmd.Value)
+	})
+}
+
+// toggle [-monitor name|index|pointer] [-default] [-window pointer|id|mark:NAME] property
+
+type ToggleCmd struct {
+	PropertyTarget
+	Name string `arg:"positional"`
+}
+
+func (cmd ToggleCmd) WorkspaceScoped() bool {
+	return cmd.workspaceScoped(cmd.Name)
+}
+
+func (cmd ToggleCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	prop, err := findProperty(cmd.Name)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	t, err := cmd.Target(manager, focusedMonitor, focusedWindow, selectedWindow)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	return selectedWindow, changeAll(cmd.targets(manager, prop, t), prop.Toggle)
+}
+
+// incr [-monitor name|index|pointer] [-default] [-window pointer|id|mark:NAME] property -N|+N
+
+type IncrCmd struct {
+	PropertyTarget
+	Name  string `arg:"positional"`
+	Delta string `arg:"positional"`
+}
+
+func (cmd IncrCmd) WorkspaceScoped() bool {
+	return cmd.workspaceScoped(cmd.Name)
+}
+
+func (cmd IncrCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	prop, err := findProperty(cmd.Name)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	t, err := cmd.Target(manager, focusedMonitor, focusedWindow, selectedWindow)
+	if err != nil {
+		return selectedWindow, err
+	}
+
+	if cmd.Delta == "" {
+		return selectedWindow, fmt.Errorf("usage: incr property -N|+N")
+	}
+
+	return selectedWindow, changeAll(cmd.targets(manager, prop, t), func(t muon.Target) error {
+		return prop.Incr(t, cmd.Delta)
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Requests are read one per line. Lines starting with { are JSON requests,
+// which get a JSON reply on one line:
+//
+//	{"version": 1, "command": "ratio", "args": ["0.6"]}
+//	{"version": 1, "status": "ok"}
+//	{"version": 1, "status": "error", "error": "command not found: foo"}
+//
+// A JSON request can also carry a batch of commands, which run without any
+// events in between, and get a reply with one result for each command:
+//
+//	{"version": 1, "batch": [{"command": "border-width", "args": ["2"]}, ...]}
+//	{"version": 1, "status": "ok", "results": [{"version": 1, "status": "ok"}, ...]}
+//
+// Anything else is a plain text command, answered with the output of the
+// command or the error message, after which the connection is closed. A
+// connection can carry any number of JSON requests, which are answered in
+// order.
+
+const ProtocolVersion = 1
+
+type Response struct {
+	Version int         `json:"version"`
+	Status  string      `json:"status"`
+	Error   string      `json:"error,omitempty"`
+	Output  []string    `json:"output,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Results []*Response `json:"results,omitempty"`
+	buffer  bytes.Buffer
+}
+
+func (res *Response) Write(p []byte) (int, error) {
+	return res.buffer.Write(p)
+}
+
+func (res *Response) Finish(err error) {
+	res.Status = "ok"
+	if err != nil {
+		res.Status = "error"
+		res.Error = err.Error()
+	}
+
+	for _, line := range strings.Split(res.buffer.String(), "\n") {
+		if line != "" {
+			res.Output = append(res.Output, line)
+		}
+	}
+}
+
+func (res *Response) Send(w io.Writer) error {
+	if res.Version == 0 {
+		if _, err := w.Write(res.buffer.Bytes()); err != nil || res.Error == "" {
+			return err
+		}
+
+		_, err := fmt.Fprintln(w, res.Error)
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(res)
+}
+
+func parseRequest(conn io.WriteCloser, line string) (Request, error) {
+	line = strings.TrimSpace(line)
+
+	if !strings.HasPrefix(line, "{") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return Request{}, fmt.Errorf("empty request")
+		}
+
+		return Request{Conn: conn, Command: fields[0], Args: fields[1:]}, nil
+	}
+
+	type command struct {
+		Command string   `json:"command"`
+		Args    []string `json:"args"`
+	}
+
+	var message struct {
+		command
+		Version int       `json:"version"`
+		Batch   []command `json:"batch"`
+	}
+
+	if err := json.Unmarshal([]byte(line), &message); err != nil {
+		return Request{Conn: conn, Version: ProtocolVersion}, err
+	}
+
+	if message.Version != ProtocolVersion {
+		return Request{Conn: conn, Version: ProtocolVersion}, fmt.Errorf("unsupported version: %d", message.Version)
+	}
+
+	if message.Batch != nil {
+		req := Request{Conn: conn, Batch: []Request{}, Version: message.Version}
+		for _, batched := range message.Batch {
+			req.Batch = append(req.Batch, Request{Conn: conn, Command: batched.Command, Args: batched.Args, Version: message.Version})
+		}
+
+		return req, nil
+	}
+
+	if message.Command == "" {
+		return Request{Conn: conn, Version: ProtocolVersion}, fmt.Errorf("empty request")
+	}
+
+	return Request{Conn: conn, Command: message.Command, Args: message.Args, Version: message.Version}, nil
+}
+
+// serve reads requests until the connection is closed or a plain text request
+// has been answered, and waits for each reply before reading the next one.
+func serve(conn net.Conn, commands chan<- Request, errors chan<- error) {
+	reader := bufio.NewReader(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || strings.TrimSpace(line) == "") {
+			if err != io.EOF {
+				errors <- err
+			}
+
+			conn.Close()
+			return
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		req, err := parseRequest(conn, line)
+		if err != nil {
+			res := &Response{Version: req.Version}
+			res.Finish(err)
+			res.Send(conn)
+
+			if req.Version == 0 {
+				conn.Close()
+				return
+			}
+
+			continue
+		}
+
+		req.done = make(chan bool, 1)
+		commands <- req
+
+		// The connection belongs to the subscription from now on.
+		if subscribed := <-req.done; subscribed {
+			return
+		}
+
+		if req.Version == 0 {
+			conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+
+	"yuki.no/muon/muon"
+	"yuki.no/muon/rect"
+)
+
+type WindowInfo struct {
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Instance   string    `json:"instance"`
+	Class      string    `json:"class"`
+	Role       string    `json:"role"`
+	Type       string    `json:"type"`
+	Geometry   rect.Rect `json:"geometry"`
+	Monitor    string    `json:"monitor"`
+	Workspace  string    `json:"workspace"`
+	Area       string    `json:"area"`
+	Visible    bool      `json:"visible"`
+	Focused    bool      `json:"focused"`
+	Selected   bool      `json:"selected"`
+	Floating   bool      `json:"floating"`
+	Fullscreen bool      `json:"fullscreen"`
+	Urgent     bool      `json:"urgent"`
+	Marks      []string  `json:"marks,omitempty"`
+}
+
+type LayoutInfo struct {
+	Name    string `json:"name"`
+	Focused bool   `json:"focused"`
+}
+
+type WorkspaceInfo struct {
+	Name       string       `json:"name"`
+	Focused    bool         `json:"focused"`
+	Layout     string       `json:"layout"`
+	Layouts    []LayoutInfo `json:"layouts"`
+	Ratio      float64      `json:"ratio"`
+	RootCount  int          `json:"root_count"`
+	Mirrored   bool         `json:"mirrored"`
+	Fullscreen bool         `json:"fullscreen"`
+	Windows    []WindowInfo `json:"windows,omitempty"`
+}
+
+type MonitorInfo struct {
+	Name          string          `json:"name"`
+	Focused       bool            `json:"focused"`
+	Geometry      rect.Rect       `json:"geometry"`
+	Workarea      rect.Rect       `json:"workarea"`
+	Padding       rect.Padding    `json:"padding"`
+	Reserved      rect.Padding    `json:"reserved"`
+	WindowGap     int             `json:"window_gap"`
+	BorderWidth   int             `json:"border_width"`
+	HintAlignment string          `json:"hint_alignment"`
+	Workspace     string          `json:"workspace"`
+	Workspaces    []WorkspaceInfo `json:"workspaces"`
+}
+
+func newWindowInfo(mon *muon.Monitor, ws *muon.Workspace, win, focusedWindow *muon.Window) WindowInfo {
+	info := WindowInfo{
+		Id:         fmt.Sprintf("0x%08x", win.Id),
+		Name:       win.Name,
+		Instance:   win.Instance,
+		Class:      win.Class,
+		Role:       win.Role,
+		Type:       win.Type,
+		Geometry:   win.Geometry,
+		Monitor:    mon.Name,
+		Workspace:  ws.Name,
+		Visible:    ws == mon.Workspace(),
+		Focused:    win == focusedWindow,
+		Floating:   win.Floating,
+		Fullscreen: win.Fullscreen,
+		Urgent:     win.Urgent,
+		Marks:      win.Marks,
+		Selected:   isSelected(win),
+	}
+
+	switch {
+	case win.Fullscreen:
+		info.Area = "fullscreen"
+	case win.Floating:
+		info.Area = "floating"
+	default:
+		info.Area = "stack"
+		for i, tiled := range ws.Tiled() {
+			if tiled == win && i < ws.RootCount {
+				info.Area = "root"
+			}
+		}
+	}
+
+	return info
+}
+
+func newWorkspaceInfo(mon *muon.Monitor, ws *muon.Workspace) WorkspaceInfo {
+	info := WorkspaceInfo{
+		Name:       ws.Name,
+		Focused:    ws == mon.Workspace(),
+		Ratio:      ws.Ratio,
+		RootCount:  ws.RootCount,
+		Mirrored:   ws.Mirrored,
+		Fullscreen: ws.Fullscreen,
+	}
+
+	focused := ws.Layouts.Focused()
+	if focused != nil {
+		info.Layout = focused.String()
+	}
+
+	for _, layout := range ws.Layouts.All() {
+		info.Layouts = append(info.Layouts, LayoutInfo{Name: layout.String(), Focused: layout == focused})
+	}
+
+	return info
+}
+
+func newMonitorInfo(manager *muon.Manager, mon *muon.Monitor) MonitorInfo {
+	info := MonitorInfo{
+		Name:          mon.Name,
+		Focused:       mon == manager.Focused(),
+		Geometry:      mon.Geometry,
+		Workarea:      mon.Workarea(),
+		Padding:       mon.Padding,
+		Reserved:      mon.Reserved,
+		WindowGap:     mon.WindowGap,
+		BorderWidth:   mon.BorderWidth,
+		HintAlignment: mon.HintAlignment,
+	}
+
+	if ws := mon.Workspace(); ws != nil {
+		info.Workspace = ws.Name
+	}
+
+	for _, ws := range mon.Workspaces.All() {
+		info.Workspaces = append(info.Workspaces, newWorkspaceInfo(mon, ws))
+	}
+
+	return info
+}
+
+func queryWindows(manager *muon.Manager, monitors []*muon.Monitor) []WindowInfo {
+	windows := []WindowInfo{}
+
+	var focusedWindow *muon.Window
+	if mon := manager.Focused(); mon != nil {
+		focusedWindow = mon.Focused()
+	}
+
+	for _, mon := range monitors {
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				windows = append(windows, newWindowInfo(mon, ws, win, focusedWindow))
+			}
+		}
+	}
+
+	return windows
+}
+
+func queryMonitors(manager *muon.Manager, monitors []*muon.Monitor) []MonitorInfo {
+	infos := []MonitorInfo{}
+
+	for _, mon := range monitors {
+		infos = append(infos, newMonitorInfo(manager, mon))
+	}
+
+	return infos
+}
+
+func queryTree(manager *muon.Manager, monitors []*muon.Monitor) []MonitorInfo {
+	infos := queryMonitors(manager, monitors)
+
+	var focusedWindow *muon.Window
+	if mon := manager.Focused(); mon != nil {
+		focusedWindow = mon.Focused()
+	}
+
+	for i, mon := range monitors {
+		for j, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				infos[i].Workspaces[j].Windows = append(infos[i].Workspaces[j].Windows,
+					newWindowInfo(mon, ws, win, focusedWindow),
+				)
+			}
+		}
+	}
+
+	return infos
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alexflint/go-arg"
+
+	"yuki.no/muon/muon"
+	"yuki.no/muon/protocol"
+)
+
+// CommandSpec registers a command under its name. New returns the go-arg
+// spec the arguments are parsed into, which is also what help and the shell
+// completions of muctl are built from. Values lists the keywords the first
+// positional argument accepts, if any.
+type CommandSpec struct {
+	Name    string
+	Summary string
+	Values  []string
+	New     func() Command
+}
+
+var registry []CommandSpec
+
+func register(specs ...CommandSpec) {
+	registry = append(registry, specs...)
+}
+
+func init() {
+	register(
+		CommandSpec{Name: "selected-border", Summary: "Get or set the border color of the selected window",
+			New: property("selected-border")},
+		CommandSpec{Name: "normal-border", Summary: "Get or set the border color of unfocused windows",
+			New: property("normal-border")},
+		CommandSpec{Name: "focused-border", Summary: "Get or set the border color of the focused window",
+			New: property("focused-border")},
+		CommandSpec{Name: "urgent-border", Summary: "Get or set the border color of urgent windows",
+			New: property("urgent-border")},
+		CommandSpec{Name: "border-width", Summary: "Get or set the border width",
+			New: property("border-width")},
+		CommandSpec{Name: "window-gap", Summary: "Get or set the gap between windows",
+			New: property("window-gap")},
+		CommandSpec{Name: "hint-alignment", Summary: "Get or set where windows smaller than their cell are placed",
+			Values: []string{"center", "top-left"},
+			New:    property("hint-alignment")},
+		CommandSpec{Name: "float-fixed", Summary: "Get or set whether fixed size windows float",
+			Values: []string{"toggle", "true", "false"},
+			New:    property("float-fixed")},
+		CommandSpec{Name: "root-count", Summary: "Get or change the number of windows in the root area",
+			New: property("root-count")},
+		CommandSpec{Name: "ratio", Summary: "Get or change the size of the root area",
+			New: property("ratio")},
+		CommandSpec{Name: "padding", Summary: "Get or set the padding on one side of the monitor",
+			Values: []string{"left", "right", "top", "bottom"},
+			New:    func() Command { return &PaddingCmd{} }},
+		CommandSpec{Name: "fullscreen", Summary: "Get or set whether the workspace shows one window",
+			Values: []string{"toggle", "true", "false"},
+			New:    property("fullscreen")},
+		CommandSpec{Name: "select-layout", Summary: "Get or select the layout of the workspace",
+			New: func() Command { return &SelectLayoutCmd{} }},
+		CommandSpec{Name: "reset-layout", Summary: "Reset the layout settings of the workspace",
+			New: func() Command { return &ResetLayoutCmd{} }},
+		CommandSpec{Name: "layouts", Summary: "Get or set the layouts to cycle through",
+			New: func() Command { return &LayoutsCmd{} }},
+		CommandSpec{Name: "mirror-layout", Summary: "Get or set whether the layout is mirrored",
+			Values: []string{"toggle", "true", "false"},
+			New:    property("mirrored")},
+		CommandSpec{Name: "focus-monitor", Summary: "Get or focus a monitor",
+			Values: []string{"pointer"},
+			New:    func() Command { return &FocusMonitorCmd{} }},
+		CommandSpec{Name: "focus-workspace", Summary: "Get or focus a workspace",
+			New: func() Command { return &FocusWorkspaceCmd{} }},
+		CommandSpec{Name: "send-to-workspace", Summary: "Send the selected window to another workspace",
+			New: func() Command { return &SendToWorkspaceCmd{} }},
+		CommandSpec{Name: "send-window", Summary: "Send a window to another monitor",
+			Values: []string{"pointer"},
+			New:    func() Command { return &SendWindowCmd{} }},
+		CommandSpec{Name: "swap-monitors", Summary: "Swap the visible workspaces of two monitors",
+			Values: []string{"pointer"},
+			New:    func() Command { return &SwapMonitorsCmd{} }},
+		CommandSpec{Name: "rename-workspace", Summary: "Get or set the name of the workspace",
+			New: func() Command { return &RenameWorkspaceCmd{} }},
+		CommandSpec{Name: "focus-window", Summary: "Get or focus a window",
+			Values: []string{"pointer", "urgent", "last", "history", "left", "right", "up", "down"},
+			New:    func() Command { return &FocusWindowCmd{} }},
+		CommandSpec{Name: "select-window", Summary: "Get or change the windows other commands act on",
+			New: func() Command { return &SelectWindowCmd{} }},
+		CommandSpec{Name: "root-window", Summary: "Move a window to the root area",
+			Values: []string{"pointer"},
+			New:    func() Command { return &RootWindowCmd{} }},
+		CommandSpec{Name: "move-window", Summary: "Move the selected window in the stack or to another monitor",
+			Values: []string{"pointer", "left", "right", "up", "down"},
+			New:    func() Command { return &MoveWindowCmd{} }},
+		CommandSpec{Name: "close-window", Summary: "Close a window",
+			Values: []string{"pointer"},
+			New:    func() Command { return &CloseWindowCmd{} }},
+		CommandSpec{Name: "float-window", Summary: "Get or set whether the selected window floats",
+			Values: []string{"toggle", "true", "false"},
+			New:    property("floating")},
+		CommandSpec{Name: "mark-window", Summary: "Get or add a mark of a window",
+			New: func() Command { return &MarkWindowCmd{} }},
+		CommandSpec{Name: "unmark-window", Summary: "Remove marks",
+			New: func() Command { return &UnmarkWindowCmd{} }},
+		CommandSpec{Name: "rule", Summary: "Add, remove or list window rules",
+			New: func() Command { return &RuleCmd{} }},
+		CommandSpec{Name: "reload", Summary: "Reload the config file",
+			New: func() Command { return &ReloadCmd{} }},
+		CommandSpec{Name: "subscribe", Summary: "Stream state changes",
+			Values: topics,
+			New:    func() Command { return &SubscribeCmd{} }},
+		CommandSpec{Name: "query", Summary: "Print the state as JSON",
+			Values: []string{"windows", "monitors", "layouts", "tree", "commands"},
+			New:    func() Command { return &QueryCmd{} }},
+		CommandSpec{Name: "alias", Summary: "Get, define or remove aliases",
+			New: func() Command { return &AliasCmd{} }},
+		CommandSpec{Name: "get", Summary: "Print a property, or all of them",
+			Values: propertyNames(),
+			New:    func() Command { return &GetCmd{} }},
+		CommandSpec{Name: "set", Summary: "Set a property",
+			Values: propertyNames(),
+			New:    func() Command { return &SetCmd{} }},
+		CommandSpec{Name: "toggle", Summary: "Toggle a bool property",
+			Values: propertyNames(),
+			New:    func() Command { return &ToggleCmd{} }},
+		CommandSpec{Name: "incr", Summary: "Add to a number property",
+			Values: propertyNames(),
+			New:    func() Command { return &IncrCmd{} }},
+		CommandSpec{Name: "help", Summary: "List commands or describe one",
+			New: func() Command { return &HelpCmd{} }},
+	)
+}
+
+// property registers the command named after a property.
+func property(name string) func() Command {
+	return func() Command { return &PropertyCmd{name: name} }
+}
+
+func propertyNames() []string {
+	var names []string
+	for _, prop := range muon.Properties() {
+		names = append(names, prop.Name)
+	}
+
+	return names
+}
+
+func findCommand(name string) *CommandSpec {
+	for i := range registry {
+		if registry[i].Name == name {
+			return &registry[i]
+		}
+	}
+
+	return nil
+}
+
+func newCommand(name string) Command {
+	if spec := findCommand(name); spec != nil {
+		return spec.New()
+	}
+
+	return nil
+}
+
+// writeHelp prints the usage go-arg builds from the spec, with flags written
+// the way muon takes them.
+func writeHelp(w io.Writer, spec *CommandSpec) error {
+	cmd := spec.New()
+
+	parser, err := arg.NewParser(arg.Config{Program: spec.Name}, cmd)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	parser.WriteHelp(&buffer)
+
+	help := buffer.String()
+	for _, flag := range append(newCommandInfo(spec.Name, reflect.TypeOf(cmd).Elem()).Flags, "-help") {
+		help = regexp.MustCompile(`(^|[\s\[])-`+regexp.QuoteMeta(flag)+`\b`).ReplaceAllString(help, "${1}"+flag)
+	}
+
+	fmt.Fprintln(w, spec.Summary)
+	_, err = io.WriteString(w, help)
+
+	return err
+}
+
+func newCommandInfo(name string, t reflect.Type) protocol.CommandInfo {
+	info := protocol.CommandInfo{Name: name, Flags: []string{}, Positionals: []string{}}
+
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				walk(field.Type)
+				continue
+			}
+
+			tag := field.Tag.Get("arg")
+
+			switch {
+			case tag == "-":
+			case strings.HasPrefix(tag, "subcommand:"):
+				info.Subcommands = append(info.Subcommands,
+					newCommandInfo(strings.TrimPrefix(tag, "subcommand:"), field.Type.Elem()),
+				)
+			case tag == "positional":
+				info.Positionals = append(info.Positionals, strings.ToUpper(field.Name))
+			default:
+				info.Flags = append(info.Flags, "-"+strings.ToLower(field.Name))
+			}
+		}
+	}
+
+	walk(t)
+	return info
+}
+
+func queryCommands() []protocol.CommandInfo {
+	infos := []protocol.CommandInfo{}
+
+	for _, spec := range registry {
+		info := newCommandInfo(spec.Name, reflect.TypeOf(spec.New()).Elem())
+		info.Summary, info.Values = spec.Summary, spec.Values
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// help [command]
+
+type HelpCmd struct {
+	Command string `arg:"positional"`
+}
+
+func (cmd HelpCmd) Run(req Request,
+	manager *muon.Manager, focusedMonitor *muon.Monitor,
+	focusedWindow, selectedWindow *muon.Window,
+) (*muon.Window, error) {
+	if cmd.Command != "" {
+		spec := findCommand(cmd.Command)
+		if spec == nil {
+			return selectedWindow, fmt.Errorf("command not found: %s", cmd.Command)
+		}
+
+		return selectedWindow, writeHelp(req, spec)
+	}
+
+	w := tabwriter.NewWriter(req, 0, 8, 2, ' ', 0)
+	for _, spec := range registry {
+		fmt.Fprintf(w, "%s\t%s\n", spec.Name, spec.Summary)
+	}
+
+	return selectedWindow, w.Flush()
+}
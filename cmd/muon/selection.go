@@ -0,0 +1,116 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"yuki.no/muon/muon"
+	"yuki.no/muon/xlib"
+)
+
+// The selection is a set of windows that commands act on instead of the
+// focused one, and the only record of what is selected. Commands are given
+// the window selected last, and return a selected window to keep the
+// selection, a window that isn't selected to select only that one, or nil to
+// clear it. The selection is cleared after selection-timeout milliseconds
+// without a command, unless the timeout is 0.
+
+var selection []*muon.Window
+
+func isSelected(win *muon.Window) bool {
+	for _, selected := range selection {
+		if selected == win {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lastSelected() *muon.Window {
+	if len(selection) == 0 {
+		return nil
+	}
+
+	return selection[len(selection)-1]
+}
+
+// The selection is never changed in place, so that earlier values of it stay
+// valid.
+
+func addSelection(win *muon.Window) {
+	if !isSelected(win) {
+		selection = append(selection[:len(selection):len(selection)], win)
+	}
+}
+
+func removeSelection(win *muon.Window) {
+	var windows []*muon.Window
+	for _, selected := range selection {
+		if selected != win {
+			windows = append(windows, selected)
+		}
+	}
+
+	selection = windows
+}
+
+// updateSelection applies the window a command returned.
+func updateSelection(win *muon.Window) {
+	switch {
+	case win == nil:
+		selection = nil
+	case !isSelected(win):
+		selection = []*muon.Window{win}
+	}
+}
+
+// pruneSelection drops windows that are no longer managed.
+func pruneSelection(manager *muon.Manager) {
+	for _, win := range selection {
+		if managed, _, _ := manager.FindWindow(win.Id); managed == nil {
+			removeSelection(win)
+		}
+	}
+}
+
+func unselectedBorder(manager *muon.Manager, win *muon.Window) muon.Color {
+	if mon := manager.Focused(); mon != nil && mon.Focused() == win {
+		return manager.FocusedBorder
+	}
+
+	return manager.Border(win)
+}
+
+func clearSelection(manager *muon.Manager) {
+	for _, win := range selection {
+		xlib.SetBorderColor(win.Id, unselectedBorder(manager, win).Value)
+	}
+
+	selection = nil
+}
+
+// resetSelection repaints the windows that were selected before a command
+// or are now, and restarts the timeout.
+func resetSelection(manager *muon.Manager, timer *time.Timer, previous []*muon.Window) {
+	if len(selection) != len(previous) || (len(previous) != 0 && previous[len(previous)-1] != lastSelected()) {
+		zap.S().Infow("select", "windows", selection)
+	}
+
+	for _, win := range previous {
+		if !isSelected(win) {
+			xlib.SetBorderColor(win.Id, unselectedBorder(manager, win).Value)
+		}
+	}
+
+	for _, win := range selection {
+		xlib.SetBorderColor(win.Id, manager.SelectedBorder.Value)
+	}
+
+	if len(selection) != 0 && manager.SelectionTimeout > 0 {
+		timer.Reset(time.Duration(manager.SelectionTimeout) * time.Millisecond)
+	} else {
+		timer.Stop()
+	}
+}
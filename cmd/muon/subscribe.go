@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/xgb/xproto"
+
+	"yuki.no/muon/muon"
+)
+
+// Subscribers get one line per change, starting with the topic:
+//
+//	focus MONITOR ID|none
+//	map ID MONITOR
+//	unmap ID
+//	title ID NAME
+//	workspace MONITOR NAME
+//	layout MONITOR NAME
+//	fullscreen MONITOR|ID true|false
+//	ratio MONITOR RATIO
+//	monitor add|remove NAME
+//
+// JSON subscribers get each change as an object with the topic and its
+// arguments instead. Changes are found by comparing the state before and
+// after every event and command, so they are reported no matter what caused
+// them.
+
+var topics = []string{"focus", "map", "unmap", "title", "workspace", "layout", "fullscreen", "ratio", "monitor"}
+
+type Subscription struct {
+	Request
+	Topics map[string]bool
+	queue  chan change
+	done   chan struct{}
+}
+
+var (
+	subscriptions []*Subscription
+	lastState     *state
+)
+
+type windowState struct {
+	Id         xproto.Window
+	Name       string
+	Monitor    string
+	Fullscreen bool
+}
+
+type monitorState struct {
+	Name       string
+	Workspace  string
+	Layout     string
+	Fullscreen bool
+	Ratio      float64
+}
+
+type change struct {
+	Version int      `json:"version"`
+	Topic   string   `json:"topic"`
+	Args    []string `json:"args"`
+}
+
+func (c change) String() string {
+	return strings.Join(append([]string{c.Topic}, c.Args...), " ")
+}
+
+type state struct {
+	Monitor  string
+	Focused  xproto.Window
+	Monitors []monitorState
+	Windows  []windowState
+}
+
+func newState(manager *muon.Manager) *state {
+	s := new(state)
+
+	if mon := manager.Focused(); mon != nil {
+		s.Monitor = mon.Name
+		if win := mon.Focused(); win != nil {
+			s.Focused = win.Id
+		}
+	}
+
+	for _, mon := range manager.Monitors.All() {
+		ms := monitorState{Name: mon.Name}
+
+		if ws := mon.Workspace(); ws != nil {
+			ms.Workspace = ws.Name
+			ms.Fullscreen = ws.Fullscreen
+			ms.Ratio = ws.Ratio
+
+			if layout := ws.Layouts.Focused(); layout != nil {
+				ms.Layout = layout.String()
+			}
+		}
+
+		s.Monitors = append(s.Monitors, ms)
+
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				s.Windows = append(s.Windows, windowState{
+					Id: win.Id, Name: win.Name, Monitor: mon.Name, Fullscreen: win.Fullscreen,
+				})
+			}
+		}
+	}
+
+	return s
+}
+
+func windowId(id xproto.Window) string {
+	if id == 0 {
+		return "none"
+	}
+
+	return fmt.Sprintf("0x%08x", id)
+}
+
+// changes describes how the state went from previous to s.
+func (s *state) changes(previous *state) []change {
+	var changes []change
+
+	add := func(topic string, args ...interface{}) {
+		c := change{Version: ProtocolVersion, Topic: topic}
+		for _, arg := range args {
+			c.Args = append(c.Args, fmt.Sprint(arg))
+		}
+
+		changes = append(changes, c)
+	}
+
+	monitors := make(map[string]monitorState)
+	for _, ms := range previous.Monitors {
+		monitors[ms.Name] = ms
+	}
+
+	current := make(map[string]bool)
+	for _, ms := range s.Monitors {
+		current[ms.Name] = true
+
+		before, ok := monitors[ms.Name]
+		if !ok {
+			add("monitor", "add", ms.Name)
+			continue
+		}
+
+		if ms.Workspace != before.Workspace {
+			add("workspace", ms.Name, ms.Workspace)
+		}
+
+		if ms.Layout != before.Layout {
+			add("layout", ms.Name, ms.Layout)
+		}
+
+		if ms.Fullscreen != before.Fullscreen {
+			add("fullscreen", ms.Name, ms.Fullscreen)
+		}
+
+		if ms.Ratio != before.Ratio {
+			add("ratio", ms.Name, strconv.FormatFloat(ms.Ratio, 'f', -1, 64))
+		}
+	}
+
+	for _, ms := range previous.Monitors {
+		if !current[ms.Name] {
+			add("monitor", "remove", ms.Name)
+		}
+	}
+
+	windows := make(map[xproto.Window]windowState)
+	for _, ws := range previous.Windows {
+		windows[ws.Id] = ws
+	}
+
+	managed := make(map[xproto.Window]bool)
+	for _, ws := range s.Windows {
+		managed[ws.Id] = true
+
+		before, ok := windows[ws.Id]
+		if !ok {
+			add("map", windowId(ws.Id), ws.Monitor)
+			continue
+		}
+
+		if ws.Name != before.Name {
+			add("title", windowId(ws.Id), ws.Name)
+		}
+
+		if ws.Fullscreen != before.Fullscreen {
+			add("fullscreen", windowId(ws.Id), ws.Fullscreen)
+		}
+	}
+
+	for _, ws := range previous.Windows {
+		if !managed[ws.Id] {
+			add("unmap", windowId(ws.Id))
+		}
+	}
+
+	if s.Monitor != previous.Monitor || s.Focused != previous.Focused {
+		add("focus", s.Monitor, windowId(s.Focused))
+	}
+
+	return changes
+}
+
+func subscribe(manager *muon.Manager, req Request, names []string) error {
+	sub := &Subscription{
+		Request: req,
+		Topics:  make(map[string]bool),
+		queue:   make(chan change, 64),
+		done:    make(chan struct{}),
+	}
+
+	for _, name := range names {
+		found := false
+		for _, topic := range topics {
+			found = found || topic == name
+		}
+
+		if !found {
+			return fmt.Errorf("topic not found: %s", name)
+		}
+
+		sub.Topics[name] = true
+	}
+
+	if len(subscriptions) == 0 {
+		lastState = newState(manager)
+	}
+
+	subscriptions = append(subscriptions, sub)
+	go sub.run()
+
+	return nil
+}
+
+func subscribed(req Request) bool {
+	for _, sub := range subscriptions {
+		if sub.Conn == req.Conn {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (sub *Subscription) run() {
+	defer close(sub.done)
+	defer sub.Conn.Close()
+
+	encoder := json.NewEncoder(sub.Conn)
+
+	for change := range sub.queue {
+		var err error
+		if sub.Version == 0 {
+			_, err = fmt.Fprintln(sub.Conn, change)
+		} else {
+			err = encoder.Encode(change)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// send queues a change for the subscriber, and reports false if it has gone
+// away or can't keep up.
+func (sub *Subscription) send(change change) bool {
+	if len(sub.Topics) != 0 && !sub.Topics[change.Topic] {
+		return true
+	}
+
+	select {
+	case <-sub.done:
+		return false
+	case sub.queue <- change:
+		return true
+	default:
+		return false
+	}
+}
+
+func publish(manager *muon.Manager) {
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	current := newState(manager)
+	changes := current.changes(lastState)
+	lastState = current
+
+	var active []*Subscription
+
+	for _, sub := range subscriptions {
+		ok := true
+		for _, change := range changes {
+			if ok = sub.send(change); !ok {
+				break
+			}
+		}
+
+		if ok {
+			active = append(active, sub)
+		} else {
+			close(sub.queue)
+		}
+	}
+
+	subscriptions = active
+}
@@ -0,0 +1,180 @@
+package muon
+
+import (
+	"yuki.no/muon/rect"
+)
+
+type Direction int
+
+const (
+	Left Direction = iota
+	Right
+	Up
+	Down
+)
+
+func ParseDirection(name string) (Direction, bool) {
+	switch name {
+	case "left":
+		return Left, true
+	case "right":
+		return Right, true
+	case "up":
+		return Up, true
+	case "down":
+		return Down, true
+	}
+
+	return 0, false
+}
+
+// nearest returns the index of the rectangle that lies closest to from in the
+// given direction, or -1 if there is none. Rectangles that overlap from along
+// the other axis are preferred over those that are closer but off to the side.
+func nearest(from rect.Rect, candidates []rect.Rect, dir Direction) int {
+	var (
+		best                   = -1
+		bestOffset, bestGap    int
+		bestDistance, distance int
+	)
+
+	for i, to := range candidates {
+		var gap, offset int
+
+		switch dir {
+		case Left:
+			if to.X+to.W/2 >= from.X {
+				continue
+			}
+
+			gap = span(to.Y, to.H, from.Y, from.H)
+			offset = from.X - to.X - to.W
+			distance = abs(to.Y + to.H/2 - from.Y - from.H/2)
+
+		case Right:
+			if to.X+to.W/2 < from.X+from.W {
+				continue
+			}
+
+			gap = span(to.Y, to.H, from.Y, from.H)
+			offset = to.X - from.X - from.W
+			distance = abs(to.Y + to.H/2 - from.Y - from.H/2)
+
+		case Up:
+			if to.Y+to.H/2 >= from.Y {
+				continue
+			}
+
+			gap = span(to.X, to.W, from.X, from.W)
+			offset = from.Y - to.Y - to.H
+			distance = abs(to.X + to.W/2 - from.X - from.W/2)
+
+		case Down:
+			if to.Y+to.H/2 < from.Y+from.H {
+				continue
+			}
+
+			gap = span(to.X, to.W, from.X, from.W)
+			offset = to.Y - from.Y - from.H
+			distance = abs(to.X + to.W/2 - from.X - from.W/2)
+		}
+
+		offset = maxInt(0, offset)
+
+		if best == -1 || gap < bestGap ||
+			(gap == bestGap && offset < bestOffset) ||
+			(gap == bestGap && offset == bestOffset && distance < bestDistance) {
+			best, bestGap, bestOffset, bestDistance = i, gap, offset, distance
+		}
+	}
+
+	return best
+}
+
+// span returns the distance between two ranges, which is zero when they
+// overlap.
+func span(a, aSize, b, bSize int) int {
+	switch {
+	case a+aSize <= b:
+		return b - a - aSize
+	case b+bSize <= a:
+		return a - b - bSize
+	}
+
+	return 0
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+
+	return a
+}
+
+// FindAdjacent returns the monitor next to mon in the given direction.
+func (manager *Manager) FindAdjacent(mon *Monitor, dir Direction) *Monitor {
+	var (
+		monitors   = manager.Monitors.All()
+		geometries []rect.Rect
+	)
+
+	for _, other := range monitors {
+		geometries = append(geometries, other.Geometry)
+	}
+
+	if i := nearest(mon.Geometry, geometries, dir); i != -1 && monitors[i] != mon {
+		return monitors[i]
+	}
+
+	return nil
+}
+
+// FindNeighbour returns the window next to win in the given direction, using
+// the geometry from the last arrange. When the workspace has nothing further
+// in that direction, the search continues on the adjacent monitor, in which
+// case the window may be nil if that monitor shows an empty workspace.
+func (manager *Manager) FindNeighbour(mon *Monitor, win *Window, dir Direction) (*Window, *Monitor) {
+	from := mon.Workarea()
+	if win != nil {
+		from = win.Geometry
+	}
+
+	if ws := mon.Workspace(); win != nil && !ws.Fullscreen && ws.Layouts.Focused() != nil {
+		if neighbour := nearestWindow(from, ws, win, dir); neighbour != nil {
+			return neighbour, mon
+		}
+	}
+
+	adjacent := manager.FindAdjacent(mon, dir)
+	if adjacent == nil {
+		return nil, nil
+	}
+
+	ws := adjacent.Workspace()
+	if ws.Fullscreen || ws.Layouts.Focused() == nil {
+		return ws.Focused(), adjacent
+	}
+
+	return nearestWindow(from, ws, nil, dir), adjacent
+}
+
+func nearestWindow(from rect.Rect, ws *Workspace, exclude *Window, dir Direction) *Window {
+	var (
+		windows    []*Window
+		geometries []rect.Rect
+	)
+
+	for _, win := range ws.Windows.All() {
+		if win != exclude && !win.Fullscreen {
+			windows = append(windows, win)
+			geometries = append(geometries, win.Geometry)
+		}
+	}
+
+	if i := nearest(from, geometries, dir); i != -1 {
+		return windows[i]
+	}
+
+	return nil
+}
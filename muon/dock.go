@@ -0,0 +1,171 @@
+package muon
+
+import (
+	"math"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"go.uber.org/zap"
+
+	"yuki.no/muon/rect"
+	"yuki.no/muon/xlib"
+)
+
+type Strut struct {
+	Left, Right, Top, Bottom int
+
+	LeftStartY, LeftEndY     int
+	RightStartY, RightEndY   int
+	TopStartX, TopEndX       int
+	BottomStartX, BottomEndX int
+}
+
+type Dock struct {
+	Id    xproto.Window
+	Strut Strut
+}
+
+func NewDock(id xproto.Window) *Dock {
+	dock := new(Dock)
+
+	dock.Id = id
+	xlib.SelectInput(id, xproto.EventMaskPropertyChange)
+	dock.UpdateStrut()
+
+	return dock
+}
+
+func (dock *Dock) UpdateStrut() {
+	if values := xlib.GetCardinals(dock.Id, xlib.NetStrutPartialAtom); len(values) >= 12 {
+		dock.Strut = Strut{
+			Left: int(values[0]), Right: int(values[1]), Top: int(values[2]), Bottom: int(values[3]),
+			LeftStartY: int(values[4]), LeftEndY: int(values[5]),
+			RightStartY: int(values[6]), RightEndY: int(values[7]),
+			TopStartX: int(values[8]), TopEndX: int(values[9]),
+			BottomStartX: int(values[10]), BottomEndX: int(values[11]),
+		}
+	} else if values := xlib.GetCardinals(dock.Id, xlib.NetStrutAtom); len(values) >= 4 {
+		dock.Strut = Strut{
+			Left: int(values[0]), Right: int(values[1]), Top: int(values[2]), Bottom: int(values[3]),
+			LeftEndY: math.MaxInt32, RightEndY: math.MaxInt32,
+			TopEndX: math.MaxInt32, BottomEndX: math.MaxInt32,
+		}
+	} else {
+		dock.Strut = Strut{}
+	}
+
+	zap.S().Infow("dock", "id", dock.Id, "strut", dock.Strut)
+}
+
+// Reserved returns the space the strut takes away from a monitor, where the
+// strut is relative to the edges of the screen.
+func (strut Strut) Reserved(screen, monitor rect.Rect) rect.Padding {
+	var padding rect.Padding
+
+	overlaps := func(start, end, from, size int) bool {
+		return start < from+size && end >= from
+	}
+
+	if strut.Left > 0 && overlaps(strut.LeftStartY, strut.LeftEndY, monitor.Y, monitor.H) {
+		padding.L = maxInt(0, strut.Left-(monitor.X-screen.X))
+	}
+
+	if strut.Right > 0 && overlaps(strut.RightStartY, strut.RightEndY, monitor.Y, monitor.H) {
+		padding.R = maxInt(0, strut.Right-(screen.X+screen.W-monitor.X-monitor.W))
+	}
+
+	if strut.Top > 0 && overlaps(strut.TopStartX, strut.TopEndX, monitor.X, monitor.W) {
+		padding.T = maxInt(0, strut.Top-(monitor.Y-screen.Y))
+	}
+
+	if strut.Bottom > 0 && overlaps(strut.BottomStartX, strut.BottomEndX, monitor.X, monitor.W) {
+		padding.B = maxInt(0, strut.Bottom-(screen.Y+screen.H-monitor.Y-monitor.H))
+	}
+
+	return padding
+}
+
+func (manager *Manager) FindDock(id xproto.Window) *Dock {
+	for _, dock := range manager.Docks {
+		if dock.Id == id {
+			return dock
+		}
+	}
+
+	return nil
+}
+
+func (manager *Manager) AddDock(id xproto.Window) {
+	if manager.FindDock(id) == nil {
+		manager.Docks = append(manager.Docks, NewDock(id))
+		manager.UpdateStruts()
+	}
+}
+
+func (manager *Manager) RemoveDock(id xproto.Window) bool {
+	for i, dock := range manager.Docks {
+		if dock.Id == id {
+			manager.Docks = append(manager.Docks[:i], manager.Docks[i+1:]...)
+			manager.UpdateStruts()
+			return true
+		}
+	}
+
+	return false
+}
+
+func (manager *Manager) UpdateStruts() {
+	for _, mon := range manager.updateReserved() {
+		mon.Arrange()
+	}
+}
+
+func (manager *Manager) updateReserved() []*Monitor {
+	var changed []*Monitor
+
+	screen, err := xlib.GetGeometry(xlib.RootWindow)
+	if err != nil {
+		return nil
+	}
+
+	workarea := screen
+
+	for _, mon := range manager.Monitors.All() {
+		var reserved rect.Padding
+
+		for _, dock := range manager.Docks {
+			padding := dock.Strut.Reserved(screen, mon.Geometry)
+			reserved.L = maxInt(reserved.L, padding.L)
+			reserved.R = maxInt(reserved.R, padding.R)
+			reserved.T = maxInt(reserved.T, padding.T)
+			reserved.B = maxInt(reserved.B, padding.B)
+		}
+
+		if reserved != mon.Reserved {
+			mon.Reserved = reserved
+			changed = append(changed, mon)
+		}
+	}
+
+	for _, dock := range manager.Docks {
+		workarea = workarea.Pad(rect.NewPadding(
+			maxInt(0, dock.Strut.Left-(workarea.X-screen.X)),
+			maxInt(0, dock.Strut.Right-(screen.X+screen.W-workarea.X-workarea.W)),
+			maxInt(0, dock.Strut.Top-(workarea.Y-screen.Y)),
+			maxInt(0, dock.Strut.Bottom-(screen.Y+screen.H-workarea.Y-workarea.H)),
+		))
+	}
+
+	xlib.SetProperty32(xlib.RootWindow, xlib.NetWorkareaAtom, xproto.AtomCardinal,
+		uint32(workarea.X), uint32(workarea.Y), uint32(workarea.W), uint32(workarea.H),
+	)
+
+	return changed
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,154 @@
+package muon
+
+import (
+	"github.com/BurntSushi/xgb/xproto"
+
+	"yuki.no/muon/xlib"
+)
+
+type ewmhState struct {
+	check     xproto.Window
+	clients   []xproto.Window
+	stacking  []xproto.Window
+	active    xproto.Window
+	restacked bool
+}
+
+func supportedAtoms() []xproto.Atom {
+	return []xproto.Atom{
+		xlib.NetSupportedAtom,
+		xlib.NetSupportingWMCheckAtom,
+		xlib.NetNameAtom,
+		xlib.NetWindowTypeAtom,
+		xlib.NetClientListAtom,
+		xlib.NetClientListStackingAtom,
+		xlib.NetActiveWindowAtom,
+		xlib.NetStateAtom,
+		xlib.NetStateFullscreenAtom,
+		xlib.NetStateAttentionAtom,
+		xlib.NetStrutAtom,
+		xlib.NetStrutPartialAtom,
+		xlib.NetWorkareaAtom,
+	}
+}
+
+func (manager *Manager) SetupEWMH() error {
+	check, err := xlib.CreateWindow()
+	if err != nil {
+		return err
+	}
+
+	manager.ewmh.check = check
+
+	// The property changes on the check window give the first timestamp.
+	xlib.SelectInput(check, xproto.EventMaskPropertyChange)
+
+	xlib.SetWindows(check, xlib.NetSupportingWMCheckAtom, check)
+	xlib.SetString(check, xlib.NetNameAtom, "muon")
+	xlib.SetWindows(xlib.RootWindow, xlib.NetSupportingWMCheckAtom, check)
+	xlib.SetAtoms(xlib.RootWindow, xlib.NetSupportedAtom, supportedAtoms()...)
+	xlib.SetWindows(xlib.RootWindow, xlib.NetClientListAtom)
+	xlib.SetWindows(xlib.RootWindow, xlib.NetClientListStackingAtom)
+	xlib.SetWindows(xlib.RootWindow, xlib.NetActiveWindowAtom, 0)
+
+	manager.UpdateClientList()
+	manager.UpdateActiveWindow()
+
+	return nil
+}
+
+func (manager *Manager) Clients() []*Window {
+	var windows []*Window
+
+	for _, mon := range manager.Monitors.All() {
+		for _, ws := range mon.Workspaces.All() {
+			windows = append(windows, ws.Windows.All()...)
+		}
+	}
+
+	return windows
+}
+
+func (manager *Manager) UpdateClientList() {
+	var clients []xproto.Window
+	for _, win := range manager.Clients() {
+		clients = append(clients, win.Id)
+	}
+
+	if equalWindows(clients, manager.ewmh.clients) {
+		if manager.ewmh.restacked {
+			manager.UpdateClientListStacking()
+		}
+
+		return
+	}
+
+	manager.ewmh.clients = clients
+	xlib.SetWindows(xlib.RootWindow, xlib.NetClientListAtom, clients...)
+	manager.UpdateClientListStacking()
+}
+
+// Restacked notes that the stacking order may have changed, so that the next
+// UpdateClientList publishes it.
+func (manager *Manager) Restacked() {
+	manager.ewmh.restacked = true
+}
+
+func (manager *Manager) UpdateClientListStacking() {
+	manager.ewmh.restacked = false
+
+	tree, err := xproto.QueryTree(xlib.Conn, xlib.RootWindow).Reply()
+	if err != nil {
+		return
+	}
+
+	managed := make(map[xproto.Window]bool)
+	for _, id := range manager.ewmh.clients {
+		managed[id] = true
+	}
+
+	var stacking []xproto.Window
+	for _, id := range tree.Children {
+		if managed[id] {
+			stacking = append(stacking, id)
+		}
+	}
+
+	if equalWindows(stacking, manager.ewmh.stacking) {
+		return
+	}
+
+	manager.ewmh.stacking = stacking
+	xlib.SetWindows(xlib.RootWindow, xlib.NetClientListStackingAtom, stacking...)
+}
+
+func (manager *Manager) UpdateActiveWindow() {
+	var active xproto.Window
+
+	if mon := manager.Focused(); mon != nil {
+		if win := mon.Focused(); win != nil {
+			active = win.Id
+		}
+	}
+
+	if active == manager.ewmh.active {
+		return
+	}
+
+	manager.ewmh.active = active
+	xlib.SetWindows(xlib.RootWindow, xlib.NetActiveWindowAtom, active)
+}
+
+func equalWindows(a, b []xproto.Window) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
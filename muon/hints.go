@@ -0,0 +1,153 @@
+package muon
+
+import (
+	"github.com/BurntSushi/xgb/xproto"
+
+	"yuki.no/muon/xlib"
+)
+
+const (
+	hintInput   = 1 << 0
+	hintUrgency = 1 << 8
+)
+
+const (
+	hintMinSize   = 1 << 4
+	hintMaxSize   = 1 << 5
+	hintResizeInc = 1 << 6
+	hintAspect    = 1 << 7
+	hintBaseSize  = 1 << 8
+)
+
+type SizeHints struct {
+	MinW, MinH int
+	MaxW, MaxH int
+	IncW, IncH int
+	BaseW      int
+	BaseH      int
+	MinAspect  float64
+	MaxAspect  float64
+}
+
+func GetSizeHints(id xproto.Window) SizeHints {
+	var hints SizeHints
+
+	values := xlib.GetCardinals(id, xproto.AtomWmNormalHints)
+	if len(values) < 15 {
+		return hints
+	}
+
+	flags := values[0]
+
+	if flags&hintMinSize != 0 {
+		hints.MinW, hints.MinH = int(values[5]), int(values[6])
+	}
+
+	if flags&hintMaxSize != 0 {
+		hints.MaxW, hints.MaxH = int(values[7]), int(values[8])
+	}
+
+	if flags&hintResizeInc != 0 {
+		hints.IncW, hints.IncH = int(values[9]), int(values[10])
+	}
+
+	if flags&hintAspect != 0 && values[11] != 0 && values[12] != 0 && values[13] != 0 && values[14] != 0 {
+		hints.MinAspect = float64(values[12]) / float64(values[11])
+		hints.MaxAspect = float64(values[13]) / float64(values[14])
+	}
+
+	if flags&hintBaseSize != 0 && len(values) >= 17 {
+		hints.BaseW, hints.BaseH = int(values[15]), int(values[16])
+	} else {
+		hints.BaseW, hints.BaseH = hints.MinW, hints.MinH
+	}
+
+	if flags&hintMinSize == 0 {
+		hints.MinW, hints.MinH = hints.BaseW, hints.BaseH
+	}
+
+	return hints
+}
+
+func (hints SizeHints) Fixed() bool {
+	return hints.MaxW != 0 && hints.MaxH != 0 && hints.MaxW == hints.MinW && hints.MaxH == hints.MinH
+}
+
+// Fit returns the largest size within w and h that satisfies the hints, as
+// described in ICCCM 4.1.2.3.
+func (hints SizeHints) Fit(w, h int) (int, int) {
+	maxW, maxH := w, h
+	baseIsMin := hints.BaseW == hints.MinW && hints.BaseH == hints.MinH
+
+	if !baseIsMin {
+		w -= hints.BaseW
+		h -= hints.BaseH
+	}
+
+	if hints.MinAspect > 0 && hints.MaxAspect > 0 && w > 0 && h > 0 {
+		if hints.MaxAspect < float64(w)/float64(h) {
+			w = int(float64(h)*hints.MaxAspect + 0.5)
+		} else if hints.MinAspect < float64(h)/float64(w) {
+			h = int(float64(w)*hints.MinAspect + 0.5)
+		}
+	}
+
+	if baseIsMin {
+		w -= hints.BaseW
+		h -= hints.BaseH
+	}
+
+	if hints.IncW > 0 {
+		w -= w % hints.IncW
+	}
+
+	if hints.IncH > 0 {
+		h -= h % hints.IncH
+	}
+
+	w = maxInt(w+hints.BaseW, hints.MinW)
+	h = maxInt(h+hints.BaseH, hints.MinH)
+
+	if hints.MaxW > 0 && w > hints.MaxW {
+		w = hints.MaxW
+	}
+
+	if hints.MaxH > 0 && h > hints.MaxH {
+		h = hints.MaxH
+	}
+
+	if w > maxW || w <= 0 {
+		w = maxW
+	}
+
+	if h > maxH || h <= 0 {
+		h = maxH
+	}
+
+	return w, h
+}
+
+// GetWMHints reports whether the window relies on the window manager to set
+// the input focus, and whether it is urgent. Windows without an input hint are
+// assumed to rely on the window manager.
+func GetWMHints(id xproto.Window) (input bool, urgent bool) {
+	values := xlib.GetCardinals(id, xproto.AtomWmHints)
+	if len(values) < 2 {
+		return true, false
+	}
+
+	input = values[0]&hintInput == 0 || values[1] != 0
+	urgent = values[0]&hintUrgency != 0
+
+	return input, urgent
+}
+
+func clearUrgencyHint(id xproto.Window) {
+	values := xlib.GetCardinals(id, xproto.AtomWmHints)
+	if len(values) == 0 || values[0]&hintUrgency == 0 {
+		return
+	}
+
+	values[0] &^= hintUrgency
+	xlib.SetProperty32(id, xproto.AtomWmHints, xproto.AtomWmHints, values...)
+}
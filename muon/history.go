@@ -0,0 +1,130 @@
+package muon
+
+import (
+	"time"
+)
+
+// The focus history lists windows from the most recently focused on, across
+// all monitors and workspaces. Cycling through it doesn't reorder it, so that
+// repeated steps reach older windows. The cycle ends when anything else takes
+// focus, or when no step was taken for cycle-timeout milliseconds, and the
+// window it ended on moves to the front.
+
+// now is replaced in tests.
+var now = time.Now
+
+func (manager *Manager) historyIndex(win *Window) int {
+	for i, other := range manager.history {
+		if other == win {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (manager *Manager) moveToFront(i int) {
+	win := manager.history[i]
+	copy(manager.history[1:i+1], manager.history[:i])
+	manager.history[0] = win
+}
+
+// Remember records that the window got focus.
+func (manager *Manager) Remember(win *Window) {
+	if manager.cycle > 0 && manager.history[manager.cycle] == win {
+		return
+	}
+
+	manager.EndCycle()
+
+	i := manager.historyIndex(win)
+	if i < 0 {
+		manager.history = append(manager.history, nil)
+		i = len(manager.history) - 1
+		manager.history[i] = win
+	}
+
+	manager.moveToFront(i)
+}
+
+func (manager *Manager) forget(win *Window) {
+	i := manager.historyIndex(win)
+	if i < 0 {
+		return
+	}
+
+	manager.history = append(manager.history[:i], manager.history[i+1:]...)
+
+	switch {
+	case i == manager.cycle:
+		manager.cycle = 0
+	case i < manager.cycle:
+		manager.cycle--
+	}
+}
+
+// LastWindow returns the window that had focus before the focused one.
+func (manager *Manager) LastWindow() *Window {
+	switch {
+	case manager.cycle > 0:
+		return manager.history[0]
+	case len(manager.history) > 1:
+		return manager.history[1]
+	}
+
+	return nil
+}
+
+// CycleHistory steps count windows further back in the history, or forward
+// for a negative count, wrapping around at the ends.
+func (manager *Manager) CycleHistory(count int) *Window {
+	if len(manager.history) == 0 {
+		return nil
+	}
+
+	timeout := time.Duration(manager.CycleTimeout) * time.Millisecond
+	if timeout > 0 && now().Sub(manager.cycledAt) > timeout {
+		manager.EndCycle()
+	}
+
+	manager.cycledAt = now()
+
+	n := len(manager.history)
+	manager.cycle = ((manager.cycle+count)%n + n) % n
+	return manager.history[manager.cycle]
+}
+
+// EndCycle moves the window a cycle through the history ended on to the
+// front.
+func (manager *Manager) EndCycle() {
+	if manager.cycle > 0 {
+		manager.moveToFront(manager.cycle)
+		manager.cycle = 0
+	}
+}
+
+// RemoveWindow stops managing a window that went away. If it had focus on
+// its workspace, the window on the workspace that was focused most recently
+// takes over.
+func (manager *Manager) RemoveWindow(win *Window, ws *Workspace) {
+	focused := ws.Focused() == win
+
+	ws.Windows.RemoveMatch(win)
+	manager.forget(win)
+
+	if !focused {
+		return
+	}
+
+	windows := make(map[*Window]bool)
+	for _, other := range ws.Windows.All() {
+		windows[other] = true
+	}
+
+	for _, other := range manager.history {
+		if windows[other] {
+			ws.Windows.FocusMatch(other)
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package muon
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestWindows(count int) []*Window {
+	windows := make([]*Window, count)
+	for i := range windows {
+		windows[i] = &Window{Name: string(rune('a' + i))}
+	}
+
+	return windows
+}
+
+func historyNames(manager *Manager) string {
+	var names string
+	for _, win := range manager.history {
+		names += win.Name
+	}
+
+	return names
+}
+
+// remember focuses the windows in order, like resetFocus does.
+func remember(manager *Manager, windows ...*Window) {
+	for _, win := range windows {
+		manager.Remember(win)
+	}
+}
+
+func TestRemember(t *testing.T) {
+	manager := &Manager{}
+	w := newTestWindows(4)
+
+	remember(manager, w[0], w[1], w[2], w[1], w[3])
+	if names := historyNames(manager); names != "dbca" {
+		t.Errorf("history = %s, want dbca", names)
+	}
+
+	if last := manager.LastWindow(); last != w[1] {
+		t.Errorf("last window = %v, want b", last)
+	}
+
+	manager.forget(w[1])
+	if names := historyNames(manager); names != "dca" {
+		t.Errorf("history = %s after forget, want dca", names)
+	}
+}
+
+func TestCycleHistory(t *testing.T) {
+	tests := []struct {
+		steps []int
+		want  string
+		order string
+	}{
+		{[]int{1}, "c", "cdba"},
+		{[]int{1, 1}, "b", "bdca"},
+		{[]int{1, 1, 1}, "a", "adcb"},
+		{[]int{1, 1, 1, 1}, "d", "dcba"},
+		{[]int{-1}, "a", "adcb"},
+		{[]int{2, -1}, "c", "cdba"},
+		{[]int{5}, "c", "cdba"},
+	}
+
+	for _, test := range tests {
+		manager := &Manager{CycleTimeout: 1000}
+		w := newTestWindows(4)
+		remember(manager, w...)
+
+		var win *Window
+		for _, step := range test.steps {
+			win = manager.CycleHistory(step)
+			manager.Remember(win)
+		}
+
+		if win.Name != test.want {
+			t.Errorf("cycle %v = %s, want %s", test.steps, win.Name, test.want)
+		}
+
+		// The cycle keeps the order until it ends.
+		if names := historyNames(manager); names != "dcba" {
+			t.Errorf("cycle %v: history = %s during the cycle, want dcba", test.steps, names)
+		}
+
+		manager.EndCycle()
+		if names := historyNames(manager); names != test.order {
+			t.Errorf("cycle %v: history = %s, want %s", test.steps, names, test.order)
+		}
+	}
+}
+
+func TestCycleEnds(t *testing.T) {
+	defer func() { now = time.Now }()
+
+	clock := time.Now()
+	now = func() time.Time { return clock }
+
+	tests := []struct {
+		name    string
+		timeout int
+		end     func(manager *Manager, w []*Window)
+		want    string
+	}{
+		{"focus", 1000, func(manager *Manager, w []*Window) { manager.Remember(w[0]) }, "c"},
+		{"timeout", 1000, func(manager *Manager, w []*Window) { clock = clock.Add(2 * time.Second) }, "d"},
+		{"no timeout", 0, func(manager *Manager, w []*Window) { clock = clock.Add(time.Hour) }, "b"},
+		{"quick step", 1000, func(manager *Manager, w []*Window) { clock = clock.Add(500 * time.Millisecond) }, "b"},
+	}
+
+	for _, test := range tests {
+		manager := &Manager{CycleTimeout: test.timeout}
+		w := newTestWindows(4)
+		remember(manager, w...)
+
+		manager.Remember(manager.CycleHistory(1))
+		test.end(manager, w)
+
+		if win := manager.CycleHistory(1); win.Name != test.want {
+			t.Errorf("%s: next step = %s, want %s", test.name, win.Name, test.want)
+		}
+	}
+}
+
+func TestCycleForget(t *testing.T) {
+	manager := &Manager{CycleTimeout: 1000}
+	w := newTestWindows(4)
+	remember(manager, w...)
+
+	manager.Remember(manager.CycleHistory(2))
+	manager.forget(w[3])
+
+	if win := manager.CycleHistory(1); win != w[0] {
+		t.Errorf("next step = %v, want a", win)
+	}
+
+	manager.forget(w[0])
+	if manager.cycle != 0 {
+		t.Errorf("cycle = %d after forgetting its window, want 0", manager.cycle)
+	}
+
+	if win := (&Manager{}).CycleHistory(1); win != nil {
+		t.Errorf("empty history cycled to %v", win)
+	}
+}
+
+func TestRemoveWindow(t *testing.T) {
+	manager := newTestManager("DP-1")
+	ws := manager.Focused().Workspace()
+	w := newTestWindows(3)
+
+	for _, win := range w {
+		ws.Windows.Insert(win)
+	}
+
+	remember(manager, w[0], w[2], w[1])
+	ws.Windows.FocusMatch(w[1])
+
+	manager.RemoveWindow(w[1], ws)
+	if focused := ws.Focused(); focused != w[2] {
+		t.Errorf("focused = %v, want c", focused)
+	}
+
+	if names := historyNames(manager); names != "ca" {
+		t.Errorf("history = %s, want ca", names)
+	}
+}
@@ -4,7 +4,18 @@ import "yuki.no/muon/rect"
 
 type Layout interface {
 	String() string
-	Layout(monitor *Monitor, windowCount int) []rect.Rect
+	Layout(monitor *Monitor, workspace *Workspace, windowCount int) []rect.Rect
+}
+
+func NewLayout(name string) Layout {
+	switch name {
+	case "horizontal":
+		return NewHorizontalLayout()
+	case "vertical":
+		return NewVerticalLayout()
+	}
+
+	return nil
 }
 
 type HorizontalLayout struct{}
@@ -17,11 +28,11 @@ func (_ HorizontalLayout) String() string {
 	return "horizontal"
 }
 
-func (_ HorizontalLayout) Layout(monitor *Monitor, windowCount int) []rect.Rect {
+func (_ HorizontalLayout) Layout(monitor *Monitor, workspace *Workspace, windowCount int) []rect.Rect {
 	var (
 		windows   []rect.Rect
-		geometry  = monitor.Geometry.Pad(monitor.Padding)
-		rootCount = monitor.RootCount
+		geometry  = monitor.Workarea()
+		rootCount = workspace.RootCount
 		subCount  = windowCount - rootCount
 		border    = monitor.BorderWidth * 2
 	)
@@ -40,14 +51,14 @@ func (_ HorizontalLayout) Layout(monitor *Monitor, windowCount int) []rect.Rect
 
 	var x, y, w, h, s, r int
 
-	r = int(float64(geometry.H) * monitor.Ratio)
-	s = geometry.W / monitor.RootCount
+	r = int(float64(geometry.H) * workspace.Ratio)
+	s = geometry.W / workspace.RootCount
 	x = geometry.X
 	y = geometry.Y
 	w = s - border
 	h = geometry.H - border
 
-	if monitor.Mirrored && subCount > 0 {
+	if workspace.Mirrored && subCount > 0 {
 		y = geometry.Y + geometry.H - r
 	}
 
@@ -74,7 +85,7 @@ func (_ HorizontalLayout) Layout(monitor *Monitor, windowCount int) []rect.Rect
 	w = s - border
 	h = geometry.H - border - r - monitor.WindowGap
 
-	if monitor.Mirrored {
+	if workspace.Mirrored {
 		y = geometry.Y
 	}
 
@@ -100,11 +111,11 @@ func (_ VerticalLayout) String() string {
 	return "vertical"
 }
 
-func (_ VerticalLayout) Layout(monitor *Monitor, windowCount int) []rect.Rect {
+func (_ VerticalLayout) Layout(monitor *Monitor, workspace *Workspace, windowCount int) []rect.Rect {
 	var (
 		windows   []rect.Rect
-		geometry  = monitor.Geometry.Pad(monitor.Padding)
-		rootCount = monitor.RootCount
+		geometry  = monitor.Workarea()
+		rootCount = workspace.RootCount
 		subCount  = windowCount - rootCount
 		border    = monitor.BorderWidth * 2
 	)
@@ -123,14 +134,14 @@ func (_ VerticalLayout) Layout(monitor *Monitor, windowCount int) []rect.Rect {
 
 	var x, y, w, h, s, r int
 
-	r = int(float64(geometry.W) * monitor.Ratio)
-	s = geometry.H / monitor.RootCount
+	r = int(float64(geometry.W) * workspace.Ratio)
+	s = geometry.H / workspace.RootCount
 	x = geometry.X
 	y = geometry.Y
 	w = geometry.W - border
 	h = s - border
 
-	if monitor.Mirrored && subCount > 0 {
+	if workspace.Mirrored && subCount > 0 {
 		x = geometry.X + geometry.W - r
 	}
 
@@ -157,7 +168,7 @@ func (_ VerticalLayout) Layout(monitor *Monitor, windowCount int) []rect.Rect {
 	w = geometry.W - border - r - monitor.WindowGap
 	h = s - border
 
-	if monitor.Mirrored {
+	if workspace.Mirrored {
 		x = geometry.X
 	}
 
@@ -1,6 +1,7 @@
 //go:generate genny -in=$GOFILE -out=window_list.go gen "Item=*Window"
 //go:generate genny -in=$GOFILE -out=monitor_list.go gen "Item=*Monitor"
 //go:generate genny -in=$GOFILE -out=layout_list.go gen "Item=Layout"
+//go:generate genny -in=$GOFILE -out=workspace_list.go gen "Item=*Workspace"
 
 package muon
 
@@ -145,6 +146,10 @@ func (l *ItemList) Insert(data Item) *ItemNode {
 	return l.insertData(data, l.root.prev)
 }
 
+func (l *ItemList) InsertFront(data Item) *ItemNode {
+	return l.insertData(data, &l.root)
+}
+
 func (l *ItemList) InsertAfterFocus(data Item) *ItemNode {
 	return l.insertData(data, l.focused)
 }
@@ -1,9 +1,11 @@
 package muon
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/xgb/randr"
 	"github.com/BurntSushi/xgb/xproto"
@@ -24,24 +26,46 @@ type Color struct {
 }
 
 type Manager struct {
-	Monitors       *MonitorList
-	SelectedBorder Color
-	NormalBorder   Color
-	FocusedBorder  Color
-	WindowGap      int
-	BorderWidth    int
+	Monitors         *MonitorList
+	SelectedBorder   Color
+	NormalBorder     Color
+	FocusedBorder    Color
+	UrgentBorder     Color
+	WindowGap        int
+	BorderWidth      int
+	HintAlignment    string
+	FloatFixed       bool
+	PublishMarks     bool
+	SelectionTimeout int
+	CycleTimeout     int
+	Padding          rect.Padding
+	Ratio            float64
+	Layouts          []string
+	WorkspaceCount   int
+	Rules            []*Rule
+	Docks            []*Dock
+	detached         map[randr.Output]*Monitor
+	history          []*Window
+	cycle            int
+	cycledAt         time.Time
+	randr            bool
+	ewmh             ewmhState
 }
 
 func NewManager() (*Manager, error) {
 	manager := new(Manager)
+	manager.detached = make(map[randr.Output]*Monitor)
 	manager.Reset()
 	return manager, nil
 }
 
+// hexColor allocates colors on the X server, and is replaced in tests.
+var hexColor = xlib.HexColor
+
 func NewColor(hex string) Color {
 	var color Color
 	color.String = hex
-	color.Value = xlib.HexColor(hex)
+	color.Value = hexColor(hex)
 	return color
 }
 
@@ -49,8 +73,57 @@ func (manager *Manager) Reset() {
 	manager.SelectedBorder = NewColor("#c28ccf")
 	manager.NormalBorder = NewColor("#3f3e3b")
 	manager.FocusedBorder = NewColor("#11809e")
+	manager.UrgentBorder = NewColor("#d4634a")
 	manager.WindowGap = 3
 	manager.BorderWidth = 4
+	manager.HintAlignment = "center"
+	manager.FloatFixed = true
+	manager.PublishMarks = false
+	manager.SelectionTimeout = 1000
+	manager.CycleTimeout = 1000
+	manager.Padding = rect.NewPadding(0, 0, 0, 0)
+	manager.Ratio = 0.65
+	manager.Layouts = []string{"vertical", "horizontal"}
+	manager.WorkspaceCount = 9
+	manager.Rules = nil
+}
+
+// Border returns the color of a window that is neither focused nor selected.
+func (manager *Manager) Border(win *Window) Color {
+	if win.Urgent {
+		return manager.UrgentBorder
+	}
+
+	return manager.NormalBorder
+}
+
+// Repaint sets the border color of every window, after the colors changed.
+func (manager *Manager) Repaint(selected []*Window) {
+	var focused *Window
+	if mon := manager.Focused(); mon != nil {
+		focused = mon.Focused()
+	}
+
+	isSelected := make(map[*Window]bool)
+	for _, win := range selected {
+		isSelected[win] = true
+	}
+
+	for _, mon := range manager.Monitors.All() {
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				color := manager.Border(win)
+				switch {
+				case isSelected[win]:
+					color = manager.SelectedBorder
+				case win == focused:
+					color = manager.FocusedBorder
+				}
+
+				xlib.SetBorderColor(win.Id, color.Value)
+			}
+		}
+	}
 }
 
 func (manager *Manager) Focused() *Monitor {
@@ -59,7 +132,7 @@ func (manager *Manager) Focused() *Monitor {
 
 func (manager *Manager) Manage() error {
 	return xproto.ChangeWindowAttributesChecked(xlib.Conn, xlib.RootWindow, xproto.CwEventMask,
-		[]uint32{xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify},
+		[]uint32{xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify | xproto.EventMaskStructureNotify},
 	).Check()
 }
 
@@ -81,12 +154,17 @@ func (manager *Manager) SetupWindows() error {
 		}
 
 		window := NewWindow(manager, id)
-		xlib.SetBorderColor(window.Id, manager.NormalBorder.Value)
+		if window.Type == "dock" {
+			manager.AddDock(id)
+			continue
+		}
+
+		xlib.SetBorderColor(window.Id, manager.Border(window).Value)
 
 		if mon := manager.FindMonitor(int(geometry.X), int(geometry.Y)); mon != nil {
-			mon.Windows.Insert(window)
+			mon.Workspace().Windows.Insert(window)
 		} else if mon = manager.Monitors.Focused(); mon != nil {
-			mon.Windows.Insert(window)
+			mon.Workspace().Windows.Insert(window)
 		}
 	}
 
@@ -99,67 +177,191 @@ func (manager *Manager) SetupWindows() error {
 	return nil
 }
 
-func (manager *Manager) SetupMonitors() error {
-	var (
-		screenResources *randr.GetScreenResourcesReply
-		err             error
-		monitors        []*Monitor
-	)
+type Output struct {
+	Id       randr.Output
+	Name     string
+	Geometry rect.Rect
+}
 
-	if err := randr.Init(xlib.Conn); err != nil {
-		goto fallback
+// QueryOutputs returns the active RandR outputs, or a single output covering
+// the root window when RandR is missing, fails or has no active outputs.
+func (manager *Manager) QueryOutputs() ([]Output, error) {
+	if manager.randr {
+		outputs, err := queryRandrOutputs()
+		if err == nil {
+			return outputs, nil
+		}
+
+		zap.S().Warnw("randr", "error", err)
 	}
 
-	if screenResources, err = randr.GetScreenResources(xlib.Conn, xlib.RootWindow).Reply(); err != nil {
-		goto fallback
+	geometry, err := xlib.GetGeometry(xlib.RootWindow)
+	if err != nil {
+		return nil, err
 	}
 
+	return []Output{{Geometry: geometry}}, nil
+}
+
+func queryRandrOutputs() ([]Output, error) {
+	var outputs []Output
+
+	screenResources, err := randr.GetScreenResources(xlib.Conn, xlib.RootWindow).Reply()
+	if err != nil {
+		return nil, err
+	}
+
+loop:
 	for _, output := range screenResources.Outputs {
 		outputInfo, err := randr.GetOutputInfo(xlib.Conn, output, xproto.TimeCurrentTime).Reply()
 		if err != nil {
-			goto fallback
+			return nil, err
 		}
 
-		if outputInfo.Connection != randr.ConnectionConnected {
+		if outputInfo.Connection != randr.ConnectionConnected || outputInfo.Crtc == 0 {
 			continue
 		}
 
-	loop:
-		for _, crtc := range outputInfo.Crtcs {
-			crtcInfo, err := randr.GetCrtcInfo(xlib.Conn, crtc, xproto.TimeCurrentTime).Reply()
-			if err != nil {
-				goto fallback
-			}
+		crtcInfo, err := randr.GetCrtcInfo(xlib.Conn, outputInfo.Crtc, xproto.TimeCurrentTime).Reply()
+		if err != nil {
+			return nil, err
+		}
 
-			geometry := rect.New(int(crtcInfo.X), int(crtcInfo.Y),
-				int(crtcInfo.Width), int(crtcInfo.Height),
-			)
+		geometry := rect.New(int(crtcInfo.X), int(crtcInfo.Y),
+			int(crtcInfo.Width), int(crtcInfo.Height),
+		)
 
-			for _, existing := range monitors {
-				if existing.Geometry.X == geometry.X && existing.Geometry.Y == geometry.Y {
-					continue loop
-				}
+		for _, existing := range outputs {
+			if existing.Geometry.X == geometry.X && existing.Geometry.Y == geometry.Y {
+				continue loop
 			}
+		}
+
+		outputs = append(outputs, Output{Id: output, Name: string(outputInfo.Name), Geometry: geometry})
+	}
+
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("no active outputs")
+	}
 
-			monitors = append(monitors, NewMonitor(manager, geometry))
+	return outputs, nil
+}
+
+func (manager *Manager) SetupMonitors() error {
+	err := randr.Init(xlib.Conn)
+	if err == nil {
+		err = randr.SelectInputChecked(xlib.Conn, xlib.RootWindow, randr.NotifyMaskScreenChange).Check()
+	}
+
+	if err != nil {
+		zap.S().Warnw("randr", "error", err)
+	}
+
+	manager.randr = err == nil
+
+	_, err = manager.UpdateMonitors()
+	return err
+}
+
+// UpdateMonitors matches the current outputs against the managed monitors by
+// output id. Monitors of outputs that went away are detached with their
+// settings, and their windows move to the focused monitor. It returns the
+// monitors that were created for outputs not seen before.
+func (manager *Manager) UpdateMonitors() ([]*Monitor, error) {
+	outputs, err := manager.QueryOutputs()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		monitors []*Monitor
+		added    []*Monitor
+		existing = make(map[randr.Output]*Monitor)
+		focused  = manager.Focused()
+	)
+
+	for _, mon := range manager.Monitors.All() {
+		existing[mon.output] = mon
+	}
+
+	for _, output := range outputs {
+		mon, ok := existing[output.Id]
+
+		switch {
+		case ok:
+			delete(existing, output.Id)
+
+		case manager.detached[output.Id] != nil:
+			mon = manager.detached[output.Id]
+			delete(manager.detached, output.Id)
+			zap.S().Infow("monitor", "attached", mon)
+
+		default:
+			mon = NewMonitor(manager, output.Name, output.Geometry)
+			mon.output = output.Id
+			added = append(added, mon)
 		}
+
+		mon.Geometry = output.Geometry
+		monitors = append(monitors, mon)
 	}
 
 	sort.Sort(SortableMonitors(monitors))
 
+	manager.Monitors = NewMonitorList()
 	for _, mon := range monitors {
 		manager.Monitors.Insert(mon)
 	}
 
-	return randr.SelectInputChecked(xlib.Conn, xlib.RootWindow, randr.NotifyMaskScreenChange).Check()
+	if focused != nil && existing[focused.output] != focused {
+		manager.Monitors.FocusMatch(focused)
+	}
 
-fallback:
-	manager.Monitors.Insert(NewMonitor(manager, rect.New(0, 0,
-		int(xlib.DefaultScreen.WidthInPixels),
-		int(xlib.DefaultScreen.HeightInPixels),
-	)))
+	for _, mon := range existing {
+		manager.detachMonitor(mon, manager.Focused())
+	}
+
+	manager.updateReserved()
+
+	for _, mon := range manager.Monitors.All() {
+		mon.Arrange()
+	}
 
-	return randr.SelectInputChecked(xlib.Conn, xlib.RootWindow, randr.NotifyMaskScreenChange).Check()
+	return added, nil
+}
+
+func (manager *Manager) detachMonitor(mon *Monitor, target *Monitor) {
+	zap.S().Infow("monitor", "detached", mon, "target", target)
+
+	targetWorkspaces := target.Workspaces.All()
+
+	for i, ws := range mon.Workspaces.All() {
+		targetWorkspace := target.Workspace()
+		if i < len(targetWorkspaces) {
+			targetWorkspace = targetWorkspaces[i]
+		}
+
+		wasVisible := mon.Workspace() == ws
+		visible := target.Workspace() == targetWorkspace
+
+		for _, win := range ws.Windows.All() {
+			ws.Windows.RemoveMatch(win)
+			targetWorkspace.Windows.Insert(win)
+
+			switch {
+			case wasVisible && !visible:
+				win.Hide()
+			case !wasVisible && visible:
+				win.Show()
+			}
+
+			if win.Floating && !target.Geometry.Contains(win.Geometry.X, win.Geometry.Y) {
+				win.Geometry = target.Geometry.Center(win.Geometry.W, win.Geometry.H)
+			}
+		}
+	}
+
+	manager.detached[mon.output] = mon
 }
 
 func (manager *Manager) Setup() error {
@@ -177,7 +379,7 @@ func (manager *Manager) Setup() error {
 		mon.Arrange()
 	}
 
-	return nil
+	return manager.SetupEWMH()
 }
 
 func (manager *Manager) FindMonitor(x, y int) *Monitor {
@@ -190,32 +392,196 @@ func (manager *Manager) FindMonitor(x, y int) *Monitor {
 	return nil
 }
 
-func (manager *Manager) FindWindow(id xproto.Window) (*Window, *Monitor) {
+func (manager *Manager) FindMonitorPointer() *Monitor {
+	queryPointer, err := xproto.QueryPointer(xlib.Conn, xlib.RootWindow).Reply()
+	if err != nil {
+		return nil
+	}
+
+	return manager.FindMonitor(int(queryPointer.RootX), int(queryPointer.RootY))
+}
+
+func (manager *Manager) FindMonitorString(selector string) *Monitor {
+	switch selector {
+	case "focused":
+		return manager.Focused()
+	case "pointer":
+		return manager.FindMonitorPointer()
+	}
+
+	for _, mon := range manager.Monitors.All() {
+		if mon.Name != "" && mon.Name == selector {
+			return mon
+		}
+	}
+
+	if index, err := strconv.Atoi(selector); err == nil && index >= 1 && index <= manager.Monitors.Len() {
+		return manager.Monitors.All()[index-1]
+	}
+
+	return nil
+}
+
+func (manager *Manager) FindWindow(id xproto.Window) (*Window, *Workspace, *Monitor) {
 	for _, mon := range manager.Monitors.All() {
-		for _, win := range mon.Windows.All() {
-			if win.Id == id {
-				return win, mon
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				if win.Id == id {
+					return win, ws, mon
+				}
 			}
 		}
 	}
 
-	return nil, nil
+	return nil, nil, nil
 }
 
-func (manager *Manager) FindWindowString(id string) (*Window, *Monitor) {
+func (manager *Manager) FindWindowString(id string) (*Window, *Workspace, *Monitor) {
+	if strings.HasPrefix(id, MarkPrefix) {
+		return manager.FindMark(strings.TrimPrefix(id, MarkPrefix))
+	}
+
 	base := strings.Replace(id, "0x", "", -1)
 	if parsed, err := strconv.ParseUint(base, 16, 32); err == nil {
 		return manager.FindWindow(xproto.Window(parsed))
 	}
 
-	return nil, nil
+	return nil, nil, nil
 }
 
-func (manager *Manager) FindWindowPointer() (*Window, *Monitor) {
+func (manager *Manager) FindWindowPointer() (*Window, *Workspace, *Monitor) {
 	queryPointer, err := xproto.QueryPointer(xlib.Conn, xlib.RootWindow).Reply()
 	if err != nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	return manager.FindWindow(queryPointer.Child)
 }
+
+// FindUrgent returns the window that has been urgent the longest.
+func (manager *Manager) FindUrgent() (*Window, *Workspace, *Monitor) {
+	var (
+		urgent    *Window
+		workspace *Workspace
+		monitor   *Monitor
+	)
+
+	for _, mon := range manager.Monitors.All() {
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				if win.Urgent && (urgent == nil || win.urgentAt.Before(urgent.urgentAt)) {
+					urgent, workspace, monitor = win, ws, mon
+				}
+			}
+		}
+	}
+
+	return urgent, workspace, monitor
+}
+
+func (manager *Manager) MoveWindow(win *Window, ws *Workspace, mon *Monitor) {
+	_, source, sourceMonitor := manager.FindWindow(win.Id)
+	if source == nil || source == ws {
+		return
+	}
+
+	wasVisible := sourceMonitor.Workspace() == source
+	visible := mon.Workspace() == ws
+
+	source.Windows.RemoveMatch(win)
+	ws.Windows.Insert(win)
+
+	if win.Floating && !mon.Geometry.Contains(win.Geometry.X, win.Geometry.Y) {
+		win.Geometry = mon.Geometry.Center(win.Geometry.W, win.Geometry.H)
+	}
+
+	switch {
+	case wasVisible && !visible:
+		win.Hide()
+	case !wasVisible && visible:
+		win.Show()
+	}
+
+	if wasVisible {
+		sourceMonitor.Arrange()
+	}
+
+	if visible && !(wasVisible && mon == sourceMonitor) {
+		mon.Arrange()
+	}
+}
+
+// SwapMonitors exchanges the windows shown on two monitors, and optionally
+// the layout settings of their workspaces. Floating windows keep their place
+// relative to the monitor.
+func (manager *Manager) SwapMonitors(mon, other *Monitor, layouts bool) {
+	ws, otherWorkspace := mon.Workspace(), other.Workspace()
+	if mon == other || ws == nil || otherWorkspace == nil {
+		return
+	}
+
+	ws.Windows, otherWorkspace.Windows = otherWorkspace.Windows, ws.Windows
+
+	if layouts {
+		ws.Layouts, otherWorkspace.Layouts = otherWorkspace.Layouts, ws.Layouts
+		ws.Ratio, otherWorkspace.Ratio = otherWorkspace.Ratio, ws.Ratio
+		ws.RootCount, otherWorkspace.RootCount = otherWorkspace.RootCount, ws.RootCount
+		ws.Mirrored, otherWorkspace.Mirrored = otherWorkspace.Mirrored, ws.Mirrored
+		ws.Fullscreen, otherWorkspace.Fullscreen = otherWorkspace.Fullscreen, ws.Fullscreen
+	}
+
+	translate := func(windows *WindowList, from, to rect.Rect) {
+		for _, win := range windows.All() {
+			if win.Floating {
+				win.Geometry.X += to.X - from.X
+				win.Geometry.Y += to.Y - from.Y
+			}
+		}
+	}
+
+	translate(ws.Windows, other.Geometry, mon.Geometry)
+	translate(otherWorkspace.Windows, mon.Geometry, other.Geometry)
+
+	zap.S().Infow("swap", "monitor", mon, "other", other, "layouts", layouts)
+
+	mon.Arrange()
+	other.Arrange()
+}
+
+// Hold defers arranging monitors until Release, so that a batch of changes
+// ends with a single arrange.
+func (manager *Manager) Hold() {
+	for _, mon := range manager.Monitors.All() {
+		mon.held = true
+	}
+
+	for _, mon := range manager.detached {
+		mon.held = true
+	}
+}
+
+func (manager *Manager) Release() {
+	for _, mon := range manager.detached {
+		mon.held, mon.dirty = false, false
+	}
+
+	for _, mon := range manager.Monitors.All() {
+		mon.held = false
+		if mon.dirty {
+			mon.dirty = false
+			mon.Arrange()
+		}
+	}
+}
+
+func (manager *Manager) ShowWindows() {
+	for _, mon := range manager.Monitors.All() {
+		for _, ws := range mon.Workspaces.All() {
+			if ws != mon.Workspace() {
+				ws.Show()
+			}
+		}
+	}
+
+	xlib.Sync()
+}
@@ -0,0 +1,75 @@
+package muon
+
+import (
+	"sort"
+	"strings"
+
+	"yuki.no/muon/xlib"
+)
+
+// Marks are names for windows, which commands take as mark:NAME wherever
+// they take a window id. A name belongs to one window at a time, and a window
+// can have any number of them. With PublishMarks, the marks of a window are
+// kept in its _MUON_MARKS property.
+
+const MarkPrefix = "mark:"
+
+func (manager *Manager) FindMark(name string) (*Window, *Workspace, *Monitor) {
+	for _, mon := range manager.Monitors.All() {
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				for _, mark := range win.Marks {
+					if mark == name {
+						return win, ws, mon
+					}
+				}
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// Mark gives the name to the window, taking it from the window that had it.
+func (manager *Manager) Mark(win *Window, name string) {
+	manager.Unmark(name)
+
+	win.Marks = append(win.Marks, name)
+	sort.Strings(win.Marks)
+	manager.publish(win)
+}
+
+func (manager *Manager) Unmark(name string) bool {
+	win, _, _ := manager.FindMark(name)
+	if win == nil {
+		return false
+	}
+
+	for i, mark := range win.Marks {
+		if mark == name {
+			win.Marks = append(win.Marks[:i], win.Marks[i+1:]...)
+			break
+		}
+	}
+
+	manager.publish(win)
+	return true
+}
+
+func (manager *Manager) publish(win *Window) {
+	if manager.PublishMarks && len(win.Marks) != 0 {
+		xlib.SetString(win.Id, xlib.MarksAtom, strings.Join(win.Marks, " "))
+	} else {
+		xlib.DeleteProperty(win.Id, xlib.MarksAtom)
+	}
+}
+
+func (manager *Manager) publishMarks() {
+	for _, mon := range manager.Monitors.All() {
+		for _, ws := range mon.Workspaces.All() {
+			for _, win := range ws.Windows.All() {
+				manager.publish(win)
+			}
+		}
+	}
+}
@@ -2,7 +2,9 @@ package muon
 
 import (
 	"fmt"
+	"strconv"
 
+	"github.com/BurntSushi/xgb/randr"
 	"go.uber.org/zap"
 
 	"yuki.no/muon/rect"
@@ -10,17 +12,18 @@ import (
 )
 
 type Monitor struct {
-	Name        string
-	Windows     *WindowList
-	Layouts     *LayoutList
-	Geometry    rect.Rect
-	Fullscreen  bool
-	Mirrored    bool
-	Ratio       float64
-	RootCount   int
-	WindowGap   int
-	BorderWidth int
-	Padding     rect.Padding
+	Name          string
+	Workspaces    *WorkspaceList
+	Geometry      rect.Rect
+	WindowGap     int
+	BorderWidth   int
+	HintAlignment string
+	Padding       rect.Padding
+	Reserved      rect.Padding
+	overrides     map[string]bool
+	held          bool
+	dirty         bool
+	output        randr.Output
 }
 
 type SortableMonitors []*Monitor
@@ -37,67 +40,176 @@ func (m *Monitor) String() string {
 	}
 }
 
-func NewMonitor(manager *Manager, geometry rect.Rect) *Monitor {
+func NewMonitor(manager *Manager, name string, geometry rect.Rect) *Monitor {
 	mon := new(Monitor)
 
-	mon.Windows = NewWindowList()
-	mon.Fullscreen = false
-	mon.Padding = rect.NewPadding(0, 0, 0, 0)
-	mon.WindowGap = manager.WindowGap
-	mon.BorderWidth = manager.BorderWidth
+	mon.Name = name
+	mon.Workspaces = NewWorkspaceList()
 	mon.Geometry = geometry
 
+	for i := 1; i <= manager.WorkspaceCount; i++ {
+		mon.Workspaces.Insert(NewWorkspace(manager, strconv.Itoa(i)))
+	}
+
 	zap.S().Infow("monitor", "geometry", mon)
 
-	mon.Reset()
+	mon.Reset(manager)
 	return mon
 }
 
+func (mon *Monitor) Reset(manager *Manager) {
+	mon.Padding = manager.Padding
+	mon.WindowGap = manager.WindowGap
+	mon.BorderWidth = manager.BorderWidth
+	mon.HintAlignment = manager.HintAlignment
+	mon.overrides = nil
+
+	for _, ws := range mon.Workspaces.All() {
+		ws.Reset(manager)
+	}
+}
+
+func (mon *Monitor) Workarea() rect.Rect {
+	return mon.Geometry.Pad(mon.Reserved).Pad(mon.Padding)
+}
+
+func (mon *Monitor) Workspace() *Workspace {
+	return mon.Workspaces.Focused()
+}
+
 func (mon *Monitor) Focused() *Window {
-	return mon.Windows.Focused()
+	if ws := mon.Workspace(); ws != nil {
+		return ws.Focused()
+	}
+
+	return nil
 }
 
-func (mon *Monitor) Reset() {
-	mon.Layouts = NewLayoutList()
-	mon.Layouts.Insert(NewVerticalLayout())
-	mon.Layouts.Insert(NewHorizontalLayout())
+func (mon *Monitor) FocusWorkspace(ws *Workspace) {
+	previous := mon.Workspace()
+	if ws == previous {
+		return
+	}
+
+	mon.Workspaces.FocusMatch(ws)
+	mon.Arrange()
+	ws.Show()
 
-	mon.Mirrored = false
-	mon.Ratio = 0.65
-	mon.RootCount = 1
+	if previous != nil {
+		previous.Hide()
+	}
 }
 
 func (mon *Monitor) Arrange() {
-	layout := mon.Layouts.Focused()
+	if mon.held {
+		mon.dirty = true
+		return
+	}
 
-	zap.S().Infow("arrange", "layout", layout)
+	ws := mon.Workspace()
+	if ws == nil {
+		return
+	}
+
+	layout := ws.Layouts.Focused()
 
-	if layout == nil || mon.Fullscreen {
-		if win := mon.Focused(); win != nil {
-			geometry := mon.Geometry.Pad(mon.Padding)
+	zap.S().Infow("arrange", "workspace", ws, "layout", layout)
+
+	if layout == nil || ws.Fullscreen {
+		if win := ws.Focused(); win != nil && !win.Floating {
+			geometry := mon.Workarea()
 			win.Geometry = geometry
 
 			xlib.SetGeometry(win.Id, geometry)
 			xlib.ConfigureFromGeometry(win.Id, 0, geometry)
 			xlib.SetBorderWidth(win.Id, 0)
-			xlib.Raise(win.Id)
 		}
+	} else {
+		tiled := ws.Tiled()
+		geometries := layout.Layout(mon, ws, len(tiled))
+		borderWidth := mon.BorderWidth
+		if len(geometries) == 1 {
+			borderWidth = 0
+		}
+
+		for i, win := range tiled {
+			if win.Fullscreen {
+				win.Geometry = geometries[i]
+				continue
+			}
+
+			geometry := mon.fit(win, geometries[i])
+
+			xlib.SetBorderWidth(win.Id, borderWidth)
+			xlib.SetGeometry(win.Id, geometry)
+			xlib.ConfigureFromGeometry(win.Id, mon.BorderWidth, geometry)
+
+			win.Geometry = geometry
+			zap.S().Infow("arrange", "window", win, "geometry", win.Geometry, "root", i < ws.RootCount)
+		}
+	}
+
+	for _, win := range ws.Windows.All() {
+		switch {
+		case win.Fullscreen:
+			xlib.SetBorderWidth(win.Id, 0)
+			xlib.SetGeometry(win.Id, mon.Geometry)
+			xlib.ConfigureFromGeometry(win.Id, 0, mon.Geometry)
+
+			zap.S().Infow("arrange", "window", win, "geometry", mon.Geometry, "fullscreen", true)
+
+		case win.Floating:
+			xlib.SetBorderWidth(win.Id, mon.BorderWidth)
+			xlib.SetGeometry(win.Id, win.Geometry)
+			xlib.ConfigureFromGeometry(win.Id, mon.BorderWidth, win.Geometry)
+
+			zap.S().Infow("arrange", "window", win, "geometry", win.Geometry, "floating", true)
+		}
+	}
+
+	mon.Restack()
+}
+
+// fit shrinks a window to its size hints within the cell given by the layout,
+// and places it either centered in the cell or in the top-left corner.
+func (mon *Monitor) fit(win *Window, cell rect.Rect) rect.Rect {
+	w, h := win.Hints.Fit(cell.W, cell.H)
+
+	if mon.HintAlignment == "center" {
+		return rect.New(cell.X+(cell.W-w)/2, cell.Y+(cell.H-h)/2, w, h)
+	}
 
+	return rect.New(cell.X, cell.Y, w, h)
+}
+
+func (mon *Monitor) Restack() {
+	ws := mon.Workspace()
+	if ws == nil {
 		return
 	}
 
-	geometries := layout.Layout(mon, mon.Windows.Len())
-	borderWidth := mon.BorderWidth
-	if len(geometries) == 1 {
-		borderWidth = 0
+	focused := ws.Focused()
+	if focused != nil && !focused.Floating {
+		xlib.Raise(focused.Id)
 	}
 
-	for i, win := range mon.Windows.All() {
-		xlib.SetBorderWidth(win.Id, borderWidth)
-		xlib.SetGeometry(win.Id, geometries[i])
-		xlib.ConfigureFromGeometry(win.Id, mon.BorderWidth, geometries[i])
+	for _, win := range ws.Windows.All() {
+		if win.Floating && !win.Fullscreen && win != focused {
+			xlib.Raise(win.Id)
+		}
+	}
+
+	if focused != nil && focused.Floating && !focused.Fullscreen {
+		xlib.Raise(focused.Id)
+	}
+
+	for _, win := range ws.Windows.All() {
+		if win.Fullscreen && win != focused {
+			xlib.Raise(win.Id)
+		}
+	}
 
-		win.Geometry = geometries[i]
-		zap.S().Infow("arrange", "window", win, "geometry", win.Geometry, "root", i < mon.RootCount)
+	if focused != nil && focused.Fullscreen {
+		xlib.Raise(focused.Id)
 	}
 }
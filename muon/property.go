@@ -0,0 +1,484 @@
+package muon
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// Properties are the settings of the manager, monitors, workspaces and
+// windows, read and changed by name. A monitor property follows the default
+// on the manager until it is set on the monitor itself, and a workspace
+// property starts out with the default when the workspace is reset.
+
+type PropertyType int
+
+const (
+	IntProperty PropertyType = iota
+	FloatProperty
+	BoolProperty
+	ColorProperty
+	EnumProperty
+)
+
+func (t PropertyType) String() string {
+	return [...]string{"int", "float", "bool", "color", "enum"}[t]
+}
+
+type Scope int
+
+const (
+	ManagerScope Scope = iota
+	MonitorScope
+	WorkspaceScope
+	WindowScope
+)
+
+func (scope Scope) String() string {
+	return [...]string{"manager", "monitor", "workspace", "window"}[scope]
+}
+
+// Target is what a property is read from or written to. Default selects
+// the manager default instead of the monitor or workspace.
+type Target struct {
+	Manager  *Manager
+	Monitor  *Monitor
+	Window   *Window
+	Selected []*Window
+	Default  bool
+}
+
+type Property struct {
+	Name   string
+	Type   PropertyType
+	Scope  Scope
+	Values []string
+	Limits func(Target) (float64, float64)
+
+	// value returns a pointer to the setting in the target, or nil if the
+	// property has no default.
+	value  func(Target) interface{}
+	update func(Target)
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func between(min, max float64) func(Target) (float64, float64) {
+	return func(Target) (float64, float64) { return min, max }
+}
+
+func arrange(t Target) {
+	t.Monitor.Arrange()
+}
+
+func repaint(t Target) {
+	t.Manager.Repaint(t.Selected)
+}
+
+var properties = []*Property{
+	{
+		Name: "selected-border", Type: ColorProperty, Scope: ManagerScope, update: repaint,
+		value: func(t Target) interface{} { return &t.Manager.SelectedBorder },
+	},
+	{
+		Name: "normal-border", Type: ColorProperty, Scope: ManagerScope, update: repaint,
+		value: func(t Target) interface{} { return &t.Manager.NormalBorder },
+	},
+	{
+		Name: "focused-border", Type: ColorProperty, Scope: ManagerScope, update: repaint,
+		value: func(t Target) interface{} { return &t.Manager.FocusedBorder },
+	},
+	{
+		Name: "urgent-border", Type: ColorProperty, Scope: ManagerScope, update: repaint,
+		value: func(t Target) interface{} { return &t.Manager.UrgentBorder },
+	},
+	{
+		Name: "float-fixed", Type: BoolProperty, Scope: ManagerScope,
+		value: func(t Target) interface{} { return &t.Manager.FloatFixed },
+	},
+	{
+		Name: "publish-marks", Type: BoolProperty, Scope: ManagerScope,
+		value:  func(t Target) interface{} { return &t.Manager.PublishMarks },
+		update: func(t Target) { t.Manager.publishMarks() },
+	},
+	{
+		Name: "selection-timeout", Type: IntProperty, Scope: ManagerScope, Limits: between(0, math.MaxInt32),
+		value: func(t Target) interface{} { return &t.Manager.SelectionTimeout },
+	},
+	{
+		Name: "cycle-timeout", Type: IntProperty, Scope: ManagerScope, Limits: between(0, math.MaxInt32),
+		value: func(t Target) interface{} { return &t.Manager.CycleTimeout },
+	},
+	{
+		Name: "border-width", Type: IntProperty, Scope: MonitorScope, Limits: between(0, 64), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.BorderWidth
+			}
+			return &t.Monitor.BorderWidth
+		},
+	},
+	{
+		Name: "window-gap", Type: IntProperty, Scope: MonitorScope, Limits: between(0, 256), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.WindowGap
+			}
+			return &t.Monitor.WindowGap
+		},
+	},
+	{
+		Name: "hint-alignment", Type: EnumProperty, Scope: MonitorScope, Values: []string{"center", "top-left"}, update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.HintAlignment
+			}
+			return &t.Monitor.HintAlignment
+		},
+	},
+	{
+		Name: "padding-left", Type: IntProperty, Scope: MonitorScope, Limits: between(0, math.MaxInt32), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.Padding.L
+			}
+			return &t.Monitor.Padding.L
+		},
+	},
+	{
+		Name: "padding-right", Type: IntProperty, Scope: MonitorScope, Limits: between(0, math.MaxInt32), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.Padding.R
+			}
+			return &t.Monitor.Padding.R
+		},
+	},
+	{
+		Name: "padding-top", Type: IntProperty, Scope: MonitorScope, Limits: between(0, math.MaxInt32), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.Padding.T
+			}
+			return &t.Monitor.Padding.T
+		},
+	},
+	{
+		Name: "padding-bottom", Type: IntProperty, Scope: MonitorScope, Limits: between(0, math.MaxInt32), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.Padding.B
+			}
+			return &t.Monitor.Padding.B
+		},
+	},
+	{
+		Name: "ratio", Type: FloatProperty, Scope: WorkspaceScope, Limits: between(0.2, 0.8), update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return &t.Manager.Ratio
+			}
+			return &t.Monitor.Workspace().Ratio
+		},
+	},
+	{
+		Name: "root-count", Type: IntProperty, Scope: WorkspaceScope, update: arrange,
+		Limits: func(t Target) (float64, float64) {
+			return 1, math.Max(1, float64(len(t.Monitor.Workspace().Tiled())))
+		},
+		value: func(t Target) interface{} {
+			if t.Default {
+				return nil
+			}
+			return &t.Monitor.Workspace().RootCount
+		},
+	},
+	{
+		Name: "fullscreen", Type: BoolProperty, Scope: WorkspaceScope, update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return nil
+			}
+			return &t.Monitor.Workspace().Fullscreen
+		},
+	},
+	{
+		Name: "mirrored", Type: BoolProperty, Scope: WorkspaceScope, update: arrange,
+		value: func(t Target) interface{} {
+			if t.Default {
+				return nil
+			}
+			return &t.Monitor.Workspace().Mirrored
+		},
+	},
+	{
+		Name: "floating", Type: BoolProperty, Scope: WindowScope,
+		value: func(t Target) interface{} { return &t.Window.Floating },
+		update: func(t Target) {
+			t.Window.Float(t.Window.Floating)
+			t.Monitor.Arrange()
+		},
+	},
+}
+
+func Properties() []*Property {
+	return properties
+}
+
+func FindProperty(name string) *Property {
+	for _, prop := range properties {
+		if prop.Name == name {
+			return prop
+		}
+	}
+
+	return nil
+}
+
+func (prop *Property) pointer(t Target) (interface{}, error) {
+	switch {
+	case prop.Scope == WindowScope && t.Window == nil:
+		return nil, fmt.Errorf("no window")
+	case prop.Scope != ManagerScope && !t.Default && t.Monitor == nil:
+		return nil, fmt.Errorf("no monitor")
+	case prop.Scope == ManagerScope:
+		t.Default = false
+	}
+
+	ptr := prop.value(t)
+	if ptr == nil {
+		return nil, fmt.Errorf("no default for %s", prop.Name)
+	}
+
+	return ptr, nil
+}
+
+func (prop *Property) Get(t Target) (string, error) {
+	ptr, err := prop.pointer(t)
+	if err != nil {
+		return "", err
+	}
+
+	switch value := ptr.(type) {
+	case *int:
+		return strconv.Itoa(*value), nil
+	case *float64:
+		return strconv.FormatFloat(*value, 'f', -1, 64), nil
+	case *bool:
+		return strconv.FormatBool(*value), nil
+	case *Color:
+		return value.String, nil
+	case *string:
+		return *value, nil
+	}
+
+	return "", fmt.Errorf("unsupported property: %s", prop.Name)
+}
+
+func (prop *Property) Set(t Target, value string) error {
+	ptr, err := prop.pointer(t)
+	if err != nil {
+		return err
+	}
+
+	invalid := fmt.Errorf("invalid %s: %s", prop.Name, value)
+
+	switch ptr := ptr.(type) {
+	case *int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return invalid
+		}
+
+		if err := prop.check(t, float64(number)); err != nil {
+			return err
+		}
+
+		*ptr = number
+
+	case *float64:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return invalid
+		}
+
+		if err := prop.check(t, number); err != nil {
+			return err
+		}
+
+		*ptr = number
+
+	case *bool:
+		state, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid
+		}
+
+		*ptr = state
+
+	case *Color:
+		if !colorPattern.MatchString(value) {
+			return invalid
+		}
+
+		*ptr = NewColor(value)
+
+	case *string:
+		found := false
+		for _, allowed := range prop.Values {
+			found = found || allowed == value
+		}
+
+		if !found {
+			return invalid
+		}
+
+		*ptr = value
+	}
+
+	prop.changed(t)
+	return nil
+}
+
+func (prop *Property) Toggle(t Target) error {
+	ptr, err := prop.pointer(t)
+	if err != nil {
+		return err
+	}
+
+	state, ok := ptr.(*bool)
+	if !ok {
+		return fmt.Errorf("%s is not a bool", prop.Name)
+	}
+
+	*state = !*state
+
+	prop.changed(t)
+	return nil
+}
+
+// Incr adds delta to a number, keeping it within the limits of the property.
+func (prop *Property) Incr(t Target, delta string) error {
+	ptr, err := prop.pointer(t)
+	if err != nil {
+		return err
+	}
+
+	amount, err := strconv.ParseFloat(delta, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", prop.Name, delta)
+	}
+
+	switch ptr := ptr.(type) {
+	case *int:
+		if amount != math.Trunc(amount) {
+			return fmt.Errorf("invalid %s: %s", prop.Name, delta)
+		}
+
+		*ptr = int(prop.clamp(t, float64(*ptr)+amount))
+
+	case *float64:
+		*ptr = prop.clamp(t, *ptr+amount)
+
+	default:
+		return fmt.Errorf("%s is not a number", prop.Name)
+	}
+
+	prop.changed(t)
+	return nil
+}
+
+// Reset makes a monitor follow the manager default again.
+func (prop *Property) Reset(t Target) error {
+	if prop.Scope != MonitorScope || t.Default {
+		return fmt.Errorf("%s can't be reset", prop.Name)
+	}
+
+	if t.Monitor == nil {
+		return fmt.Errorf("no monitor")
+	}
+
+	delete(t.Monitor.overrides, prop.Name)
+	prop.follow(Target{Manager: t.Manager, Selected: t.Selected, Default: true}, t.Monitor)
+
+	return nil
+}
+
+// follow copies the manager default to the monitor.
+func (prop *Property) follow(t Target, mon *Monitor) {
+	target := Target{Manager: t.Manager, Monitor: mon, Selected: t.Selected}
+	assign(prop.value(target), prop.value(t))
+
+	if prop.update != nil {
+		prop.update(target)
+	}
+}
+
+// Follow copies the manager defaults to the settings the monitor doesn't
+// override, without arranging it.
+func (mon *Monitor) Follow(manager *Manager) {
+	for _, prop := range properties {
+		if prop.Scope == MonitorScope && !mon.overrides[prop.Name] {
+			assign(prop.value(Target{Manager: manager, Monitor: mon}), prop.value(Target{Manager: manager, Default: true}))
+		}
+	}
+}
+
+func (prop *Property) check(t Target, value float64) error {
+	if prop.Limits == nil {
+		return nil
+	}
+
+	if min, max := prop.Limits(t); value < min || value > max {
+		return fmt.Errorf("%s out of range: %v, must be between %v and %v", prop.Name, value, min, max)
+	}
+
+	return nil
+}
+
+func (prop *Property) clamp(t Target, value float64) float64 {
+	if prop.Limits == nil {
+		return value
+	}
+
+	min, max := prop.Limits(t)
+	return math.Max(min, math.Min(max, value))
+}
+
+// changed applies a new value. A new default on the manager is passed on to
+// the monitors that don't override it.
+func (prop *Property) changed(t Target) {
+	if prop.Scope == MonitorScope && t.Default {
+		for _, mon := range t.Manager.Monitors.All() {
+			if !mon.overrides[prop.Name] {
+				prop.follow(t, mon)
+			}
+		}
+
+		for _, mon := range t.Manager.detached {
+			if !mon.overrides[prop.Name] {
+				assign(prop.value(Target{Manager: t.Manager, Monitor: mon}), prop.value(t))
+			}
+		}
+
+		return
+	}
+
+	if prop.Scope == MonitorScope {
+		if t.Monitor.overrides == nil {
+			t.Monitor.overrides = make(map[string]bool)
+		}
+
+		t.Monitor.overrides[prop.Name] = true
+	}
+
+	if prop.update != nil && (prop.Scope == ManagerScope || !t.Default) {
+		prop.update(t)
+	}
+}
+
+func assign(dst, src interface{}) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
@@ -0,0 +1,271 @@
+package muon
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"yuki.no/muon/rect"
+)
+
+func init() {
+	hexColor = func(string) uint32 { return 0 }
+}
+
+// newTestManager sets up monitors that are held, so that changes don't
+// arrange windows on the X server.
+func newTestManager(names ...string) *Manager {
+	manager, _ := NewManager()
+	manager.Monitors = NewMonitorList()
+
+	for i, name := range names {
+		mon := NewMonitor(manager, name, rect.New(i*1000, 0, 1000, 1000))
+		mon.held = true
+		manager.Monitors.Insert(mon)
+	}
+
+	return manager
+}
+
+func addWindows(mon *Monitor, count int) {
+	for i := 0; i < count; i++ {
+		mon.Workspace().Windows.Insert(&Window{Id: 1})
+	}
+}
+
+func TestPropertySet(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows int
+		value   string
+		want    string
+		err     bool
+	}{
+		{"border-width", 0, "2", "2", false},
+		{"border-width", 0, "64", "64", false},
+		{"border-width", 0, "65", "4", true},
+		{"border-width", 0, "-1", "4", true},
+		{"border-width", 0, "wide", "4", true},
+		{"ratio", 0, "0.5", "0.5", false},
+		{"ratio", 0, "0.9", "0.65", true},
+		{"hint-alignment", 0, "top-left", "top-left", false},
+		{"hint-alignment", 0, "middle", "center", true},
+		{"normal-border", 0, "#112233", "#112233", false},
+		{"normal-border", 0, "red", "#3f3e3b", true},
+		{"float-fixed", 0, "false", "false", false},
+		{"float-fixed", 0, "maybe", "true", true},
+		{"fullscreen", 0, "true", "true", false},
+		{"root-count", 0, "1", "1", false},
+		{"root-count", 0, "2", "1", true},
+		{"root-count", 3, "3", "3", false},
+		{"root-count", 3, "4", "1", true},
+		{"root-count", 3, "0", "1", true},
+		{"selection-timeout", 0, "0", "0", false},
+	}
+
+	for _, test := range tests {
+		manager := newTestManager("DP-1")
+		mon := manager.Focused()
+		addWindows(mon, test.windows)
+
+		prop := FindProperty(test.name)
+		target := Target{Manager: manager, Monitor: mon}
+
+		if err := prop.Set(target, test.value); (err != nil) != test.err {
+			t.Errorf("%s %s: error = %v, want error %v", test.name, test.value, err, test.err)
+		}
+
+		if value, _ := prop.Get(target); value != test.want {
+			t.Errorf("%s %s: value = %s, want %s", test.name, test.value, value, test.want)
+		}
+	}
+}
+
+func TestPropertyIncr(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows int
+		delta   string
+		want    string
+		err     bool
+	}{
+		{"border-width", 0, "+2", "6", false},
+		{"border-width", 0, "-2", "2", false},
+		{"border-width", 0, "+100", "64", false},
+		{"border-width", 0, "-100", "0", false},
+		{"border-width", 0, "+0.5", "4", true},
+		{"border-width", 0, "more", "4", true},
+		{"ratio", 0, "+0.1", "0.75", false},
+		{"ratio", 0, "+1", "0.8", false},
+		{"ratio", 0, "-1", "0.2", false},
+		{"root-count", 0, "+1", "1", false},
+		{"root-count", 3, "+1", "2", false},
+		{"root-count", 3, "+5", "3", false},
+		{"root-count", 3, "-5", "1", false},
+		{"fullscreen", 0, "+1", "false", true},
+	}
+
+	for _, test := range tests {
+		manager := newTestManager("DP-1")
+		mon := manager.Focused()
+		addWindows(mon, test.windows)
+
+		prop := FindProperty(test.name)
+		target := Target{Manager: manager, Monitor: mon}
+
+		if err := prop.Incr(target, test.delta); (err != nil) != test.err {
+			t.Errorf("%s %s: error = %v, want error %v", test.name, test.delta, err, test.err)
+		}
+
+		// Floats are compared to two places, since 0.65+0.1 isn't 0.75.
+		value, _ := prop.Get(target)
+		if prop.Type == FloatProperty {
+			value = fmt.Sprintf("%.2f", mustParseFloat(value))
+			test.want = fmt.Sprintf("%.2f", mustParseFloat(test.want))
+		}
+
+		if value != test.want {
+			t.Errorf("%s %s: value = %s, want %s", test.name, test.delta, value, test.want)
+		}
+	}
+}
+
+func TestRootCountFloating(t *testing.T) {
+	manager := newTestManager("DP-1")
+	mon := manager.Focused()
+	addWindows(mon, 2)
+	mon.Workspace().Windows.Insert(&Window{Id: 1, Floating: true})
+
+	prop := FindProperty("root-count")
+	target := Target{Manager: manager, Monitor: mon}
+
+	if err := prop.Set(target, "3"); err == nil {
+		t.Errorf("root-count 3 succeeded with 2 tiled windows")
+	}
+
+	if err := prop.Incr(target, "+5"); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := prop.Get(target); value != "2" {
+		t.Errorf("root-count = %s, want 2", value)
+	}
+}
+
+func mustParseFloat(s string) float64 {
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(err)
+	}
+
+	return number
+}
+
+func TestPropertyToggle(t *testing.T) {
+	manager := newTestManager("DP-1")
+	target := Target{Manager: manager, Monitor: manager.Focused()}
+
+	mirrored := FindProperty("mirrored")
+	if err := mirrored.Toggle(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := mirrored.Get(target); value != "true" {
+		t.Errorf("mirrored = %s, want true", value)
+	}
+
+	if err := FindProperty("ratio").Toggle(target); err == nil {
+		t.Errorf("toggling ratio succeeded")
+	}
+}
+
+func TestPropertyDefaults(t *testing.T) {
+	manager := newTestManager("DP-1", "DP-2")
+	monitors := manager.Monitors.All()
+	prop := FindProperty("border-width")
+
+	get := func(mon *Monitor) string {
+		value, _ := prop.Get(Target{Manager: manager, Monitor: mon})
+		return value
+	}
+
+	setDefault := func(value string) {
+		if err := prop.Set(Target{Manager: manager, Default: true}, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A new default reaches every monitor that doesn't override it.
+	setDefault("6")
+	if get(monitors[0]) != "6" || get(monitors[1]) != "6" {
+		t.Errorf("border-width = %s %s, want 6 6", get(monitors[0]), get(monitors[1]))
+	}
+
+	if err := prop.Set(Target{Manager: manager, Monitor: monitors[0]}, "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	setDefault("8")
+	if get(monitors[0]) != "2" || get(monitors[1]) != "8" {
+		t.Errorf("border-width = %s %s, want 2 8", get(monitors[0]), get(monitors[1]))
+	}
+
+	if value, _ := prop.Get(Target{Manager: manager, Default: true}); value != "8" {
+		t.Errorf("default border-width = %s, want 8", value)
+	}
+
+	// Resetting makes the monitor follow the default again.
+	if err := prop.Reset(Target{Manager: manager, Monitor: monitors[0]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if get(monitors[0]) != "8" {
+		t.Errorf("border-width = %s after reset, want 8", get(monitors[0]))
+	}
+
+	setDefault("5")
+	if get(monitors[0]) != "5" {
+		t.Errorf("border-width = %s, want 5", get(monitors[0]))
+	}
+
+	// Follow skips overrides, and picks up defaults changed behind its back.
+	prop.Set(Target{Manager: manager, Monitor: monitors[1]}, "1")
+	manager.BorderWidth, manager.WindowGap = 7, 9
+
+	for _, mon := range monitors {
+		mon.Follow(manager)
+	}
+
+	if get(monitors[0]) != "7" || get(monitors[1]) != "1" {
+		t.Errorf("border-width = %s %s after follow, want 7 1", get(monitors[0]), get(monitors[1]))
+	}
+
+	if monitors[1].WindowGap != 9 {
+		t.Errorf("window-gap = %d after follow, want 9", monitors[1].WindowGap)
+	}
+}
+
+func TestPropertyReset(t *testing.T) {
+	manager := newTestManager("DP-1")
+	target := Target{Manager: manager, Monitor: manager.Focused()}
+
+	tests := []struct {
+		name   string
+		target Target
+	}{
+		{"ratio", target},
+		{"normal-border", target},
+		{"border-width", Target{Manager: manager, Default: true}},
+		{"border-width", Target{Manager: manager}},
+	}
+
+	for _, test := range tests {
+		if err := FindProperty(test.name).Reset(test.target); err == nil {
+			t.Errorf("resetting %s succeeded", test.name)
+		}
+	}
+
+	if _, err := FindProperty("root-count").Get(Target{Manager: manager, Default: true}); err == nil {
+		t.Errorf("root-count has a default")
+	}
+}
@@ -0,0 +1,107 @@
+package muon
+
+import (
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type Rule struct {
+	Instance   string
+	Class      string
+	Title      *regexp.Regexp
+	Role       string
+	Type       string
+	Monitor    string
+	Position   string
+	Floating   bool
+	Fullscreen bool
+	Focus      bool
+	Unmanaged  bool
+}
+
+func (rule *Rule) String() string {
+	var fields []string
+
+	if rule.Instance != "" {
+		fields = append(fields, "-instance", rule.Instance)
+	}
+
+	if rule.Class != "" {
+		fields = append(fields, "-class", rule.Class)
+	}
+
+	if rule.Title != nil {
+		fields = append(fields, "-title", rule.Title.String())
+	}
+
+	if rule.Role != "" {
+		fields = append(fields, "-role", rule.Role)
+	}
+
+	if rule.Type != "" {
+		fields = append(fields, "-type", rule.Type)
+	}
+
+	if rule.Monitor != "" {
+		fields = append(fields, "-monitor", rule.Monitor)
+	}
+
+	if rule.Position != "" {
+		fields = append(fields, "-position", rule.Position)
+	}
+
+	if rule.Floating {
+		fields = append(fields, "-floating")
+	}
+
+	if rule.Fullscreen {
+		fields = append(fields, "-fullscreen")
+	}
+
+	if rule.Focus {
+		fields = append(fields, "-focus")
+	}
+
+	if rule.Unmanaged {
+		fields = append(fields, "-unmanaged")
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (rule *Rule) Match(win *Window) bool {
+	return (rule.Instance == "" || rule.Instance == win.Instance) &&
+		(rule.Class == "" || rule.Class == win.Class) &&
+		(rule.Title == nil || rule.Title.MatchString(win.Name)) &&
+		(rule.Role == "" || rule.Role == win.Role) &&
+		(rule.Type == "" || rule.Type == win.Type)
+}
+
+func (manager *Manager) MatchRules(win *Window) Rule {
+	var result Rule
+
+	for _, rule := range manager.Rules {
+		if !rule.Match(win) {
+			continue
+		}
+
+		zap.S().Infow("rule", "window", win, "rule", rule)
+
+		if rule.Monitor != "" {
+			result.Monitor = rule.Monitor
+		}
+
+		if rule.Position != "" {
+			result.Position = rule.Position
+		}
+
+		result.Floating = result.Floating || rule.Floating
+		result.Fullscreen = result.Fullscreen || rule.Fullscreen
+		result.Focus = result.Focus || rule.Focus
+		result.Unmanaged = result.Unmanaged || rule.Unmanaged
+	}
+
+	return result
+}
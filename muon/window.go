@@ -2,6 +2,8 @@ package muon
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/xgb/xproto"
 	"go.uber.org/zap"
@@ -11,10 +13,22 @@ import (
 )
 
 type Window struct {
-	Id        xproto.Window
-	Name      string
-	Geometry  rect.Rect
-	Protocols map[xproto.Atom]bool
+	Id         xproto.Window
+	Name       string
+	Instance   string
+	Class      string
+	Role       string
+	Type       string
+	Geometry   rect.Rect
+	Floating   bool
+	Fullscreen bool
+	Hints      SizeHints
+	Input      bool
+	Urgent     bool
+	Marks      []string
+	Protocols  map[xproto.Atom]bool
+	unmaps     int
+	urgentAt   time.Time
 }
 
 func (win *Window) String() string {
@@ -29,13 +43,179 @@ func NewWindow(mr *Manager, id xproto.Window) *Window {
 	win := new(Window)
 
 	win.Id = id
-	win.Name = xlib.GetString(id, xlib.NameAtom)
+	win.Name = windowName(id)
+	win.Role = xlib.GetString(id, xlib.RoleAtom)
+	win.Hints = GetSizeHints(id)
+	win.Input, win.Urgent = GetWMHints(id)
 	win.Protocols = make(map[xproto.Atom]bool)
 	for _, atom := range xlib.GetAtoms(id, xlib.ProtocolsAtom) {
 		win.Protocols[atom] = true
 	}
 
-	zap.S().Infow("window", "id", win)
+	if class := xlib.GetStrings(id, xlib.ClassAtom); len(class) == 2 {
+		win.Instance = class[0]
+		win.Class = class[1]
+	}
+
+	if types := xlib.GetAtoms(id, xlib.NetWindowTypeAtom); len(types) != 0 {
+		win.Type = windowType(types[0])
+	}
+
+	for _, atom := range xlib.GetAtoms(id, xlib.NetStateAtom) {
+		switch atom {
+		case xlib.NetStateFullscreenAtom:
+			win.Fullscreen = true
+		case xlib.NetStateAttentionAtom:
+			win.Urgent = true
+		}
+	}
+
+	if win.Urgent {
+		win.urgentAt = time.Now()
+	}
+
+	xlib.SelectInput(id, xproto.EventMaskPropertyChange)
+
+	zap.S().Infow("window", "id", win, "class", win.Class, "instance", win.Instance, "type", win.Type)
 
 	return win
 }
+
+func windowName(id xproto.Window) string {
+	if name := xlib.GetString(id, xlib.NetNameAtom); name != "" {
+		return name
+	}
+
+	return xlib.GetString(id, xlib.NameAtom)
+}
+
+func windowType(atom xproto.Atom) string {
+	return strings.ToLower(strings.TrimPrefix(xlib.GetAtomName(atom), "_NET_WM_WINDOW_TYPE_"))
+}
+
+func (win *Window) UpdateName() {
+	win.Name = windowName(win.Id)
+}
+
+func (win *Window) UpdateHints() {
+	win.Hints = GetSizeHints(win.Id)
+	zap.S().Infow("hints", "window", win, "hints", win.Hints)
+}
+
+// UpdateWMHints refreshes the input model, and marks the window urgent while
+// the urgency hint is set.
+func (win *Window) UpdateWMHints() {
+	var urgent bool
+	win.Input, urgent = GetWMHints(win.Id)
+
+	if urgent != win.Urgent {
+		win.SetUrgent(urgent)
+	}
+}
+
+// Focus follows the input model of the window as described in ICCCM 4.1.7.
+// Windows that don't accept input are only asked to take focus themselves.
+// Input focus is set at CurrentTime, so that it isn't ignored after a client
+// set focus later than the given time. WM_TAKE_FOCUS can't carry CurrentTime,
+// and is sent with the given time instead.
+func (win *Window) Focus(time xproto.Timestamp) {
+	if win.Input {
+		xlib.SetFocus(win.Id)
+	}
+
+	if _, ok := win.Protocols[xlib.TakeFocusAtom]; ok {
+		xlib.ClientMessage(win.Id, uint32(xlib.TakeFocusAtom), uint32(time))
+	}
+}
+
+func (win *Window) Show() {
+	xlib.MapWindow(win.Id)
+}
+
+func (win *Window) Hide() {
+	win.unmaps += 1
+	xlib.UnmapWindow(win.Id)
+}
+
+// Hidden consumes an UnmapNotify caused by Hide, so that hidden windows stay
+// managed.
+func (win *Window) Hidden() bool {
+	if win.unmaps > 0 {
+		win.unmaps -= 1
+		return true
+	}
+
+	return false
+}
+
+func (win *Window) Float(floating bool) {
+	if floating && (win.Geometry.W == 0 || win.Geometry.H == 0) {
+		if geometry, err := xlib.GetGeometry(win.Id); err == nil {
+			win.Geometry = geometry
+		}
+	}
+
+	win.Floating = floating
+}
+
+func (win *Window) SetFullscreen(fullscreen bool) {
+	win.Fullscreen = fullscreen
+	win.UpdateState()
+}
+
+// SetUrgent marks the window as demanding attention. Clearing it also clears
+// the urgency hint, so that the client knows it has been seen.
+func (win *Window) SetUrgent(urgent bool) {
+	if urgent && !win.Urgent {
+		win.urgentAt = time.Now()
+	}
+
+	if !urgent {
+		clearUrgencyHint(win.Id)
+	}
+
+	win.Urgent = urgent
+	win.UpdateState()
+}
+
+// UpdateState publishes the states muon keeps track of, and leaves the other
+// states of the window alone.
+func (win *Window) UpdateState() {
+	var atoms []xproto.Atom
+
+	for _, atom := range xlib.GetAtoms(win.Id, xlib.NetStateAtom) {
+		if atom != xlib.NetStateFullscreenAtom && atom != xlib.NetStateAttentionAtom {
+			atoms = append(atoms, atom)
+		}
+	}
+
+	if win.Fullscreen {
+		atoms = append(atoms, xlib.NetStateFullscreenAtom)
+	}
+
+	if win.Urgent {
+		atoms = append(atoms, xlib.NetStateAttentionAtom)
+	}
+
+	xlib.SetAtoms(win.Id, xlib.NetStateAtom, atoms...)
+}
+
+func (win *Window) Configure(event xproto.ConfigureRequestEvent) {
+	if event.ValueMask&xproto.ConfigWindowX != 0 {
+		win.Geometry.X = int(event.X)
+	}
+
+	if event.ValueMask&xproto.ConfigWindowY != 0 {
+		win.Geometry.Y = int(event.Y)
+	}
+
+	if event.ValueMask&xproto.ConfigWindowWidth != 0 {
+		win.Geometry.W = int(event.Width)
+	}
+
+	if event.ValueMask&xproto.ConfigWindowHeight != 0 {
+		win.Geometry.H = int(event.Height)
+	}
+
+	xlib.ConfigureFromRequest(event)
+}
@@ -0,0 +1,75 @@
+package muon
+
+import (
+	"go.uber.org/zap"
+)
+
+type Workspace struct {
+	Name       string
+	Windows    *WindowList
+	Layouts    *LayoutList
+	Fullscreen bool
+	Mirrored   bool
+	Ratio      float64
+	RootCount  int
+}
+
+func (ws *Workspace) String() string {
+	return ws.Name
+}
+
+func NewWorkspace(manager *Manager, name string) *Workspace {
+	ws := new(Workspace)
+
+	ws.Name = name
+	ws.Windows = NewWindowList()
+	ws.Fullscreen = false
+
+	ws.Reset(manager)
+	return ws
+}
+
+func (ws *Workspace) Focused() *Window {
+	return ws.Windows.Focused()
+}
+
+func (ws *Workspace) Tiled() []*Window {
+	var windows []*Window
+
+	for _, win := range ws.Windows.All() {
+		if !win.Floating {
+			windows = append(windows, win)
+		}
+	}
+
+	return windows
+}
+
+func (ws *Workspace) Reset(manager *Manager) {
+	ws.Layouts = NewLayoutList()
+	for _, name := range manager.Layouts {
+		if layout := NewLayout(name); layout != nil {
+			ws.Layouts.Insert(layout)
+		}
+	}
+
+	ws.Mirrored = false
+	ws.Ratio = manager.Ratio
+	ws.RootCount = 1
+}
+
+func (ws *Workspace) Show() {
+	zap.S().Infow("show", "workspace", ws)
+
+	for _, win := range ws.Windows.All() {
+		win.Show()
+	}
+}
+
+func (ws *Workspace) Hide() {
+	zap.S().Infow("hide", "workspace", ws)
+
+	for _, win := range ws.Windows.All() {
+		win.Hide()
+	}
+}
@@ -0,0 +1,13 @@
+// Package protocol holds the types muon and muctl share over the control
+// socket.
+package protocol
+
+// CommandInfo describes the arguments of a command, for completions.
+type CommandInfo struct {
+	Name        string        `json:"name"`
+	Summary     string        `json:"summary,omitempty"`
+	Flags       []string      `json:"flags"`
+	Positionals []string      `json:"positionals"`
+	Values      []string      `json:"values,omitempty"`
+	Subcommands []CommandInfo `json:"subcommands,omitempty"`
+}
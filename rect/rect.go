@@ -5,11 +5,17 @@ import (
 )
 
 type Rect struct {
-	X, Y, W, H int
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"width"`
+	H int `json:"height"`
 }
 
 type Padding struct {
-	L, R, T, B int
+	L int `json:"left"`
+	R int `json:"right"`
+	T int `json:"top"`
+	B int `json:"bottom"`
 }
 
 func New(x, y, w, h int) Rect {
@@ -40,3 +46,7 @@ func (rect Rect) Contains(x, y int) bool {
 func (rect Rect) Less(other Rect) bool {
 	return rect.X < other.X || (rect.X == other.X && rect.Y < other.Y)
 }
+
+func (rect Rect) Center(w, h int) Rect {
+	return New(rect.X+(rect.W-w)/2, rect.Y+(rect.H-h)/2, w, h)
+}
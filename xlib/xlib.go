@@ -2,6 +2,7 @@ package xlib
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/xproto"
@@ -11,23 +12,53 @@ import (
 )
 
 var (
-	Conn             *xgb.Conn
-	DefaultScreen    *xproto.ScreenInfo
-	DefaultColormap  xproto.Colormap
-	RootWindow       xproto.Window
-	DeleteWindowAtom xproto.Atom
-	NameAtom         xproto.Atom
-	ProtocolsAtom    xproto.Atom
-	TransientForAtom xproto.Atom
+	Conn              *xgb.Conn
+	DefaultScreen     *xproto.ScreenInfo
+	DefaultColormap   xproto.Colormap
+	RootWindow        xproto.Window
+	DeleteWindowAtom  xproto.Atom
+	TakeFocusAtom     xproto.Atom
+	NameAtom          xproto.Atom
+	ProtocolsAtom     xproto.Atom
+	TransientForAtom  xproto.Atom
+	ClassAtom         xproto.Atom
+	RoleAtom          xproto.Atom
+	NetNameAtom       xproto.Atom
+	NetWindowTypeAtom xproto.Atom
+	Utf8StringAtom    xproto.Atom
+	MarksAtom         xproto.Atom
+
+	NetSupportedAtom          xproto.Atom
+	NetSupportingWMCheckAtom  xproto.Atom
+	NetClientListAtom         xproto.Atom
+	NetClientListStackingAtom xproto.Atom
+	NetActiveWindowAtom       xproto.Atom
+	NetStateAtom              xproto.Atom
+	NetStateFullscreenAtom    xproto.Atom
+	NetStateAttentionAtom     xproto.Atom
+	NetStrutAtom              xproto.Atom
+	NetStrutPartialAtom       xproto.Atom
+	NetWorkareaAtom           xproto.Atom
+)
+
+const (
+	NetStateRemove = 0
+	NetStateAdd    = 1
+	NetStateToggle = 2
 )
 
 func init() {
 	logger, _ := zap.NewDevelopment()
 	zap.ReplaceGlobals(logger)
+}
 
+// Connect opens the connection to the X server and interns the atoms. It is
+// called by main rather than on init, so that packages using xlib can be
+// tested without a display.
+func Connect() error {
 	var err error
 	if Conn, err = xgb.NewConn(); err != nil {
-		zap.S().Fatal(err)
+		return err
 	}
 
 	DefaultScreen = xproto.Setup(Conn).DefaultScreen(Conn)
@@ -35,15 +66,48 @@ func init() {
 	DefaultColormap = DefaultScreen.DefaultColormap
 
 	DeleteWindowAtom = MustInternAtom("WM_DELETE_WINDOW")
+	TakeFocusAtom = MustInternAtom("WM_TAKE_FOCUS")
 	NameAtom = MustInternAtom("WM_NAME")
 	ProtocolsAtom = MustInternAtom("WM_PROTOCOLS")
 	TransientForAtom = MustInternAtom("WM_TRANSIENT_FOR")
+	ClassAtom = MustInternAtom("WM_CLASS")
+	RoleAtom = MustInternAtom("WM_WINDOW_ROLE")
+	NetNameAtom = MustInternAtom("_NET_WM_NAME")
+	NetWindowTypeAtom = MustInternAtom("_NET_WM_WINDOW_TYPE")
+	Utf8StringAtom = MustInternAtom("UTF8_STRING")
+	MarksAtom = MustInternAtom("_MUON_MARKS")
+
+	NetSupportedAtom = MustInternAtom("_NET_SUPPORTED")
+	NetSupportingWMCheckAtom = MustInternAtom("_NET_SUPPORTING_WM_CHECK")
+	NetClientListAtom = MustInternAtom("_NET_CLIENT_LIST")
+	NetClientListStackingAtom = MustInternAtom("_NET_CLIENT_LIST_STACKING")
+	NetActiveWindowAtom = MustInternAtom("_NET_ACTIVE_WINDOW")
+	NetStateAtom = MustInternAtom("_NET_WM_STATE")
+	NetStateFullscreenAtom = MustInternAtom("_NET_WM_STATE_FULLSCREEN")
+	NetStateAttentionAtom = MustInternAtom("_NET_WM_STATE_DEMANDS_ATTENTION")
+	NetStrutAtom = MustInternAtom("_NET_WM_STRUT")
+	NetStrutPartialAtom = MustInternAtom("_NET_WM_STRUT_PARTIAL")
+	NetWorkareaAtom = MustInternAtom("_NET_WORKAREA")
+
+	return nil
 }
 
 func MapWindow(id xproto.Window) {
 	xproto.MapWindow(Conn, id)
 }
 
+func UnmapWindow(id xproto.Window) {
+	xproto.UnmapWindow(Conn, id)
+}
+
+func Sync() {
+	xproto.GetInputFocus(Conn).Reply()
+}
+
+func SelectInput(id xproto.Window, mask uint32) {
+	xproto.ChangeWindowAttributes(Conn, id, xproto.CwEventMask, []uint32{mask})
+}
+
 func SetFocus(id xproto.Window) {
 	xproto.SetInputFocus(Conn, xproto.InputFocusPointerRoot, id, xproto.TimeCurrentTime)
 }
@@ -67,6 +131,15 @@ func SetGeometry(id xproto.Window, geometry rect.Rect) {
 	})
 }
 
+func GetGeometry(id xproto.Window) (rect.Rect, error) {
+	reply, err := xproto.GetGeometry(Conn, xproto.Drawable(id)).Reply()
+	if err != nil {
+		return rect.Rect{}, err
+	}
+
+	return rect.New(int(reply.X), int(reply.Y), int(reply.Width), int(reply.Height)), nil
+}
+
 func ConfigureFromGeometry(id xproto.Window, borderWidth int, geometry rect.Rect) {
 	configureNotify := xproto.ConfigureNotifyEvent{
 		Event:            id,
@@ -150,9 +223,66 @@ func MustInternAtom(name string) xproto.Atom {
 }
 
 func InternAtom(name string) (xproto.Atom, error) {
-	reply, err := xproto.InternAtom(Conn, true, uint16(len(name)), name).Reply()
+	reply, err := xproto.InternAtom(Conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+
+	return reply.Atom, nil
+}
+
+func GetAtomName(atom xproto.Atom) string {
+	if reply, err := xproto.GetAtomName(Conn, atom).Reply(); err == nil {
+		return reply.Name
+	}
 
-	return reply.Atom, err
+	return ""
+}
+
+func CreateWindow() (xproto.Window, error) {
+	id, err := xproto.NewWindowId(Conn)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, xproto.CreateWindowChecked(Conn, 0, id, RootWindow, -1, -1, 1, 1, 0,
+		xproto.WindowClassInputOnly, 0, xproto.CwOverrideRedirect, []uint32{1},
+	).Check()
+}
+
+func SetProperty32(window xproto.Window, atom, kind xproto.Atom, values ...uint32) {
+	data := make([]byte, len(values)*4)
+	for i, value := range values {
+		xgb.Put32(data[i*4:], value)
+	}
+
+	xproto.ChangeProperty(Conn, xproto.PropModeReplace, window, atom, kind, 32, uint32(len(values)), data)
+}
+
+func SetAtoms(window xproto.Window, atom xproto.Atom, atoms ...xproto.Atom) {
+	values := make([]uint32, len(atoms))
+	for i, value := range atoms {
+		values[i] = uint32(value)
+	}
+
+	SetProperty32(window, atom, xproto.AtomAtom, values...)
+}
+
+func SetWindows(window xproto.Window, atom xproto.Atom, windows ...xproto.Window) {
+	values := make([]uint32, len(windows))
+	for i, value := range windows {
+		values[i] = uint32(value)
+	}
+
+	SetProperty32(window, atom, xproto.AtomWindow, values...)
+}
+
+func SetString(window xproto.Window, atom xproto.Atom, value string) {
+	xproto.ChangeProperty(Conn, xproto.PropModeReplace, window, atom, Utf8StringAtom, 8, uint32(len(value)), []byte(value))
+}
+
+func DeleteProperty(window xproto.Window, atom xproto.Atom) {
+	xproto.DeleteProperty(Conn, window, atom)
 }
 
 func GetProperty(window xproto.Window, atom xproto.Atom) (*xproto.GetPropertyReply, error) {
@@ -161,8 +291,11 @@ func GetProperty(window xproto.Window, atom xproto.Atom) (*xproto.GetPropertyRep
 
 func GetPropertyValue(window xproto.Window, atom xproto.Atom) ([]byte, error) {
 	reply, err := GetProperty(window, atom)
+	if err != nil {
+		return nil, err
+	}
 
-	return reply.Value, err
+	return reply.Value, nil
 }
 
 func GetAtoms(window xproto.Window, atom xproto.Atom) []xproto.Atom {
@@ -179,6 +312,19 @@ func GetAtoms(window xproto.Window, atom xproto.Atom) []xproto.Atom {
 	return nil
 }
 
+func GetCardinals(window xproto.Window, atom xproto.Atom) []uint32 {
+	if property, err := GetPropertyValue(window, atom); err == nil {
+		values := make([]uint32, len(property)/4)
+		for i := range values {
+			values[i] = xgb.Get32(property[i*4:])
+		}
+
+		return values
+	}
+
+	return nil
+}
+
 func GetString(window xproto.Window, atom xproto.Atom) string {
 	if property, err := GetPropertyValue(window, atom); err == nil {
 		return string(property)
@@ -187,6 +333,14 @@ func GetString(window xproto.Window, atom xproto.Atom) string {
 	return ""
 }
 
+func GetStrings(window xproto.Window, atom xproto.Atom) []string {
+	if property, err := GetPropertyValue(window, atom); err == nil && len(property) != 0 {
+		return strings.Split(strings.TrimRight(string(property), "\x00"), "\x00")
+	}
+
+	return nil
+}
+
 func GetWindow(window xproto.Window, atom xproto.Atom) xproto.Window {
 	if property, err := GetPropertyValue(window, atom); err == nil && len(property) != 0 {
 		return xproto.Window(xgb.Get32(property))